@@ -0,0 +1,100 @@
+// Package main is the StealthQUIC-VPN client.
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/transport"
+)
+
+// ClientConfig holds the client's YAML configuration.
+type ClientConfig struct {
+	ServerAddr string `yaml:"server_addr"`
+	MTU        int    `yaml:"mtu"`
+
+	TLS struct {
+		SNI         []string `yaml:"sni"`
+		Fingerprint string   `yaml:"fingerprint"` // "chrome_120", "chrome_124", "firefox_115", "firefox_125", "safari_16", "safari_17", "edge_106" or "randomized"
+	} `yaml:"tls"`
+
+	Encryption outercipher.Config `yaml:"encryption"`
+
+	// TunnelMode selects how TUN PDUs are carried to the server: one of
+	// TunnelModeDatagram (default), TunnelModeStreamMux or
+	// TunnelModeStreamPerPacket.
+	TunnelMode string `yaml:"tunnel_mode"`
+
+	Transport transport.Config `yaml:"transport"`
+
+	FEC struct {
+		Enabled       bool    `yaml:"enabled"`
+		Codec         string  `yaml:"codec"` // "tetrys" or "raptorq"
+		MaxRedundancy float64 `yaml:"max_redundancy"`
+	} `yaml:"fec"`
+
+	Multipath struct {
+		Mode string `yaml:"mode"` // "failover", "latency" or "round-robin"; empty disables multipath
+		// RepairDuplication is the fraction of FEC repair symbols mirrored
+		// to standby paths, e.g. 1.0 mirrors every repair symbol.
+		RepairDuplication float64 `yaml:"repair_duplication"`
+	} `yaml:"multipath"`
+
+	Keepalive struct {
+		Interval time.Duration `yaml:"interval"`
+		Timeout  time.Duration `yaml:"timeout"`
+	} `yaml:"keepalive"`
+}
+
+// LoadClientConfig loads the client configuration from a YAML file,
+// filling in sane defaults for anything left unset.
+func LoadClientConfig(path string) (*ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config := &ClientConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if config.ServerAddr == "" {
+		return nil, fmt.Errorf("server_addr is required in config")
+	}
+	if config.MTU <= 0 {
+		config.MTU = 1500
+	}
+	if config.TLS.Fingerprint == "" {
+		config.TLS.Fingerprint = "randomized"
+	}
+	if config.FEC.Codec == "" {
+		config.FEC.Codec = "tetrys"
+	}
+	if config.FEC.MaxRedundancy <= 0 {
+		config.FEC.MaxRedundancy = 0.2
+	}
+	if config.Keepalive.Interval <= 0 {
+		config.Keepalive.Interval = 30 * time.Second
+	}
+	if config.Keepalive.Timeout <= 0 {
+		config.Keepalive.Timeout = 10 * time.Second
+	}
+	if config.Multipath.Mode != "" && config.Multipath.RepairDuplication <= 0 {
+		config.Multipath.RepairDuplication = 1.0
+	}
+	if config.TunnelMode == "" {
+		config.TunnelMode = TunnelModeDatagram
+	}
+	switch config.TunnelMode {
+	case TunnelModeDatagram, TunnelModeStreamMux, TunnelModeStreamPerPacket:
+	default:
+		return nil, fmt.Errorf("unknown tunnel mode %q", config.TunnelMode)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+// negotiateOuterCipher builds this side's outer cipher and exchanges
+// nonce-salt handshake messages with the peer over dataStream, before any
+// TUN traffic flows on it. It returns a nil Cipher, and does nothing on
+// the wire, when cfg is disabled.
+func negotiateOuterCipher(dataStream quic.Stream, cfg outercipher.Config) (*outercipher.Cipher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	oc, err := outercipher.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up outer cipher: %w", err)
+	}
+	if err := writeFrame(dataStream, oc.HandshakeMessage()); err != nil {
+		return nil, fmt.Errorf("failed to send outer cipher handshake: %w", err)
+	}
+	peerMsg, err := readFrame(dataStream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer outer cipher handshake: %w", err)
+	}
+	if err := oc.ApplyPeerHandshake(peerMsg); err != nil {
+		return nil, fmt.Errorf("invalid peer outer cipher handshake: %w", err)
+	}
+	return oc, nil
+}
+
+// rotationMagic prefixes an ad hoc control stream carrying a key rotation
+// announcement, distinguishing it from a keepalive ping.
+var rotationMagic = []byte("rotate:")
+
+// announceRotation tells the peer about a key rotation this side just
+// performed, over a short-lived stream, the same way keepalive pings use
+// one-off streams rather than the dedicated TUN data stream. The message
+// is written raw, like a keepalive ping, rather than length-prefixed,
+// since it is the only thing ever written to this stream.
+func announceRotation(conn quic.Connection, msg []byte) error {
+	stream, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to open rotation announcement stream: %w", err)
+	}
+	defer safestream.Wrap(stream).Close()
+	_, err = stream.Write(append(append([]byte{}, rotationMagic...), msg...))
+	return err
+}
+
+// maybeRotateCipher checks whether oc is due for a rotation and, if so,
+// performs it and announces the new epoch to the peer. Errors are logged
+// rather than propagated, since a failed rotation just means the current
+// epoch keeps being used, not something worth tearing down the
+// connection over.
+func maybeRotateCipher(conn quic.Connection, oc *outercipher.Cipher) {
+	if oc == nil || !oc.NeedsRotation() {
+		return
+	}
+	msg, err := oc.Rotate()
+	if err != nil {
+		log.Printf("Outer cipher rotation failed: %v", err)
+		return
+	}
+	if err := announceRotation(conn, msg); err != nil {
+		log.Printf("Failed to announce outer cipher rotation: %v", err)
+	}
+}
+
+// handleRotationAnnouncement reads a rotation message off an ad hoc
+// control stream accepted by acceptControlStreams and applies it to oc,
+// if one is configured.
+func handleRotationAnnouncement(oc *outercipher.Cipher, payload []byte) {
+	if oc == nil {
+		return
+	}
+	if err := oc.ApplyPeerHandshake(payload); err != nil {
+		log.Printf("Failed to apply peer key rotation: %v", err)
+	}
+}
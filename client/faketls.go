@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/stealth"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/transport"
+)
+
+// DialWithFakeTLS dials the server with a ClientHello shaped to mimic the
+// browser fingerprint selected in config.TLS.Fingerprint (including the
+// newer chrome_124/firefox_125/safari_17 profiles in pkg/stealth),
+// rotating the SNI on every call from config.TLS.SNI, over whichever
+// transport obfuscator config.Transport.Mode selects. The QUIC transport
+// parameters quic-go negotiates on this connection are not reordered to
+// match the profile - see stealth.QUICTransportParameters for why.
+func DialWithFakeTLS(addr string, config *ClientConfig) (quic.Connection, error) {
+	return dialFakeTLS(addr, config, nil)
+}
+
+// dialFakeTLS is DialWithFakeTLS with the local UDP socket pinned to
+// localAddr (or left to the kernel's choice when nil), so multipath can
+// dial one QUIC connection per local interface.
+func dialFakeTLS(addr string, config *ClientConfig, localAddr *net.UDPAddr) (quic.Connection, error) {
+	tlsConfig, err := stealth.TLSConfig(stealth.Profile(config.TLS.Fingerprint), config.TLS.SNI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build stealth TLS config: %w", err)
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve server address: %w", err)
+	}
+	udpConn, err := net.ListenUDP("udp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local UDP socket: %w", err)
+	}
+
+	obfuscator, err := transport.New(config.Transport)
+	if err != nil {
+		udpConn.Close()
+		return nil, fmt.Errorf("failed to set up transport obfuscator: %w", err)
+	}
+	packetConn := obfuscator.WrapPacketConn(udpConn)
+
+	quicConfig := &quic.Config{
+		HandshakeIdleTimeout: 5 * time.Second,
+		MaxIncomingStreams:   1000,
+		EnableDatagrams:      true,
+	}
+
+	conn, err := quic.Dial(context.Background(), packetConn, udpAddr, tlsConfig, quicConfig)
+	if err != nil {
+		return nil, fmt.Errorf("QUIC connection failed: %w", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/fec"
+)
+
+// newFECPair returns a matched encoder/decoder for the configured codec.
+func newFECPair(codec string, redundancy float64) (fec.FEC, fec.FEC, error) {
+	cfg := fec.Config{MaxRedundancy: redundancy}
+	switch codec {
+	case "", "tetrys":
+		enc, dec := fec.NewTetrys(cfg)
+		return enc, dec, nil
+	case "raptorq":
+		enc, dec := fec.NewRaptorQ(cfg)
+		return enc, dec, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown FEC codec %q", codec)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+// startKeepaliveTicker periodically sends a keepalive message over a
+// short-lived stream and waits for the server's acknowledgement.
+func startKeepaliveTicker(ctx context.Context, conn quic.Connection, config *ClientConfig) {
+	ticker := time.NewTicker(config.Keepalive.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sendKeepalive(conn, config); err != nil {
+				log.Printf("keepalive failed: %v", err)
+			}
+		}
+	}
+}
+
+// sendKeepalive sends a keepalive message and waits up to the configured
+// timeout for the server's response.
+func sendKeepalive(conn quic.Connection, config *ClientConfig) error {
+	stream, err := conn.OpenStream()
+	if err != nil {
+		return fmt.Errorf("failed to open keepalive stream: %w", err)
+	}
+	defer safestream.Wrap(stream).Close()
+
+	if _, err := stream.Write([]byte("keepalive")); err != nil {
+		return fmt.Errorf("failed to send keepalive: %w", err)
+	}
+
+	stream.SetReadDeadline(time.Now().Add(config.Keepalive.Timeout))
+	buffer := make([]byte, 1024)
+	n, err := stream.Read(buffer)
+	if err != nil {
+		return fmt.Errorf("keepalive response failed: %w", err)
+	}
+
+	if response := string(buffer[:n]); response != "alive" {
+		return fmt.Errorf("unexpected keepalive response: %s", response)
+	}
+	return nil
+}
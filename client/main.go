@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/songgao/water"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/fec"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/multipath"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+func main() {
+	configPath := flag.String("config", "client/config.yaml", "Path to client configuration file")
+	flag.Parse()
+
+	config, err := LoadClientConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load client config: %v", err)
+	}
+
+	if config.Multipath.Mode != "" {
+		runMultipath(config)
+		return
+	}
+
+	conn, err := DialWithFakeTLS(config.ServerAddr, config)
+	if err != nil {
+		log.Fatalf("QUIC connection failed: %v", err)
+	}
+	defer conn.CloseWithError(0, "client shutdown")
+
+	iface, err := setupTUN(conn, config.MTU)
+	if err != nil {
+		log.Fatalf("TUN creation failed: %v", err)
+	}
+	defer iface.Close()
+
+	dataStream, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to open TUN data stream: %v", err)
+	}
+
+	oc, err := negotiateOuterCipher(dataStream, config.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to negotiate outer cipher: %v", err)
+	}
+
+	encoder, decoder, err := newFECPair(config.FEC.Codec, config.FEC.MaxRedundancy)
+	if err != nil {
+		log.Fatalf("Failed to set up FEC codec: %v", err)
+	}
+	safeDecoder := fec.NewSafeDecoder(decoder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go HandleTUNTraffic(ctx, iface, conn, dataStream, encoder, oc, config.TunnelMode, nil)
+	go HandleQUICDataStream(ctx, dataStream, iface, safeDecoder, oc)
+	go HandleQUICDatagrams(ctx, conn, iface, safeDecoder, oc)
+	go startKeepaliveTicker(ctx, conn, config)
+	go acceptControlStreams(ctx, conn, iface, safeDecoder, oc)
+
+	select {}
+}
+
+// acceptControlStreams accepts ad hoc streams the server opens outside
+// the dedicated TUN data stream - key rotation announcements and,
+// under TunnelModeStreamPerPacket, individual TUN PDUs - and applies
+// them to oc or iface as appropriate.
+func acceptControlStreams(ctx context.Context, conn quic.Connection, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher) {
+	for {
+		raw, err := conn.AcceptStream(ctx)
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("Failed to accept control stream: %v", err)
+			}
+			return
+		}
+		go func() {
+			stream := safestream.Wrap(raw)
+			defer stream.Close()
+			buffer := make([]byte, 4096)
+			n, err := stream.Read(buffer)
+			if err != nil {
+				return
+			}
+			switch {
+			case len(rotationMagic) <= n && string(buffer[:len(rotationMagic)]) == string(rotationMagic):
+				handleRotationAnnouncement(oc, buffer[len(rotationMagic):n])
+			case len(perPacketMagic) <= n && string(buffer[:len(perPacketMagic)]) == string(perPacketMagic):
+				handlePerPacketPDU(buffer[len(perPacketMagic):n], iface, decoder, oc)
+			case len(probeMagic) <= n && string(buffer[:len(probeMagic)]) == string(probeMagic):
+				if _, err := stream.Write(probeAck); err != nil {
+					log.Printf("Control stream write error: %v", err)
+				}
+			}
+		}()
+	}
+}
+
+// handlePerPacketPDU decrypts and FEC-decodes a single TUN PDU received
+// over a TunnelModeStreamPerPacket stream and writes any recovered IP
+// packets back to iface.
+func handlePerPacketPDU(frame []byte, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher) {
+	decrypted, err := decryptPDU(frame, oc)
+	if err != nil {
+		log.Printf("Decryption failed: %v", err)
+		return
+	}
+	for _, pkt := range decoder.Decode(decrypted) {
+		if _, err := iface.Write(pkt); err != nil {
+			log.Printf("Failed to write to TUN: %v", err)
+		}
+	}
+}
+
+// runMultipath dials one QUIC connection per local interface and keeps
+// the TUN data stream pinned to whichever path config.Multipath.Mode
+// currently selects as primary, switching seamlessly when that path goes
+// down and duplicating FEC repair symbols to the remaining standbys.
+func runMultipath(config *ClientConfig) {
+	manager, err := dialMultipath(config.ServerAddr, config)
+	if err != nil {
+		log.Fatalf("Multipath dial failed: %v", err)
+	}
+
+	// The TUN interface is created once and stays put across path
+	// switches, so it can't be sized from any one path's negotiated
+	// datagram size; pass nil to keep the static configured MTU.
+	iface, err := setupTUN(nil, config.MTU)
+	if err != nil {
+		log.Fatalf("TUN creation failed: %v", err)
+	}
+	defer iface.Close()
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	go manager.Watch(watchCtx, 5*time.Second)
+
+	dup := newRepairDuplicator(manager, config.Multipath.RepairDuplication)
+
+	var active *multipath.Path
+	for {
+		primary := manager.Primary()
+		if primary == nil || primary == active {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		log.Printf("multipath: switching active path to %s", primary.Name)
+		active = primary
+
+		sessionCtx, cancelSession := context.WithCancel(context.Background())
+		dataStream, err := primary.Conn.OpenStreamSync(sessionCtx)
+		if err != nil {
+			log.Printf("multipath: failed to open TUN data stream on %s: %v", primary.Name, err)
+			cancelSession()
+			active = nil
+			continue
+		}
+
+		oc, err := negotiateOuterCipher(dataStream, config.Encryption)
+		if err != nil {
+			log.Printf("multipath: failed to negotiate outer cipher on %s: %v", primary.Name, err)
+			cancelSession()
+			active = nil
+			continue
+		}
+
+		encoder, decoder, err := newFECPair(config.FEC.Codec, config.FEC.MaxRedundancy)
+		if err != nil {
+			log.Fatalf("Failed to set up FEC codec: %v", err)
+		}
+		safeDecoder := fec.NewSafeDecoder(decoder)
+
+		go HandleTUNTraffic(sessionCtx, iface, primary.Conn, dataStream, encoder, oc, config.TunnelMode, dup.DuplicateRepair)
+		go HandleQUICDataStream(sessionCtx, dataStream, iface, safeDecoder, oc)
+		go HandleQUICDatagrams(sessionCtx, primary.Conn, iface, safeDecoder, oc)
+		go startKeepaliveTicker(sessionCtx, primary.Conn, config)
+		go acceptControlStreams(sessionCtx, primary.Conn, iface, safeDecoder, oc)
+		go watchFailover(manager, primary, cancelSession)
+	}
+}
+
+// watchFailover cancels a session's context as soon as its path stops
+// being the manager's primary, triggering runMultipath to switch over.
+func watchFailover(manager *multipath.Manager, path *multipath.Path, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if manager.Primary() != path {
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/multipath"
+)
+
+// dialMultipath dials one QUIC connection per usable local network
+// interface and returns a Manager that selects among them according to
+// config.Multipath.Mode.
+func dialMultipath(addr string, config *ClientConfig) (*multipath.Manager, error) {
+	localIPs, err := multipath.LocalAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate local interfaces: %w", err)
+	}
+	if len(localIPs) == 0 {
+		return nil, fmt.Errorf("no usable local interfaces found for multipath")
+	}
+
+	manager := multipath.NewManager(multipath.Mode(config.Multipath.Mode))
+
+	dialed := 0
+	for _, ip := range localIPs {
+		localAddr := &net.UDPAddr{IP: ip}
+		conn, err := dialFakeTLS(addr, config, localAddr)
+		if err != nil {
+			log.Printf("multipath: failed to dial via %s: %v", ip, err)
+			continue
+		}
+		manager.Add(multipath.NewPath(ip.String(), localAddr, conn))
+		dialed++
+	}
+	if dialed == 0 {
+		return nil, fmt.Errorf("failed to dial any multipath candidate")
+	}
+	return manager, nil
+}
+
+// repairDuplicator mirrors a configurable fraction of FEC repair symbols
+// onto every standby path's dedicated stream, so a short-lived outage on
+// the active path doesn't cost a recovery opportunity.
+type repairDuplicator struct {
+	manager    *multipath.Manager
+	redundancy float64
+
+	mu      sync.Mutex
+	sent    float64
+	streams map[*multipath.Path]quic.Stream
+}
+
+// newRepairDuplicator creates a duplicator that mirrors repair symbols to
+// manager's standby paths at the given redundancy ratio (e.g. 1.0 mirrors
+// every repair symbol, 0.5 mirrors every other one).
+func newRepairDuplicator(manager *multipath.Manager, redundancy float64) *repairDuplicator {
+	return &repairDuplicator{
+		manager:    manager,
+		redundancy: redundancy,
+		streams:    make(map[*multipath.Path]quic.Stream),
+	}
+}
+
+// DuplicateRepair mirrors payload to every alive standby path, subject to
+// the configured redundancy ratio.
+func (d *repairDuplicator) DuplicateRepair(payload []byte) {
+	if d.redundancy <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	d.sent += d.redundancy
+	fire := d.sent >= 1
+	if fire {
+		d.sent--
+	}
+	d.mu.Unlock()
+	if !fire {
+		return
+	}
+
+	for _, p := range d.manager.Standbys() {
+		stream, err := d.streamFor(p)
+		if err != nil {
+			log.Printf("multipath: failed to open standby stream on %s: %v", p.Name, err)
+			continue
+		}
+		if err := writeFrame(stream, payload); err != nil {
+			log.Printf("multipath: failed to mirror repair symbol to %s: %v", p.Name, err)
+		}
+	}
+}
+
+func (d *repairDuplicator) streamFor(p *multipath.Path) (quic.Stream, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s, ok := d.streams[p]; ok {
+		return s, nil
+	}
+	s, err := p.Conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	d.streams[p] = s
+	return s, nil
+}
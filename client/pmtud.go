@@ -0,0 +1,13 @@
+package main
+
+// probeMagic prefixes an ad hoc stream carrying a DPLPMTUD probe sent by
+// the server, distinguishing it from a keepalive ping or rotation
+// announcement on the same ad hoc stream accept loop. Only the server
+// drives path MTU discovery (see server/pmtud.go); the client just
+// acknowledges probes it receives.
+var probeMagic = []byte("probe:")
+
+// probeAck is written back as soon as one read of a probe stream returns
+// data starting with probeMagic; the rest of the probe is not awaited, so
+// the ack does not confirm that the full probe size arrived.
+var probeAck = []byte("ack")
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/quic-go/quic-go"
+	"github.com/songgao/water"
+)
+
+// setupTUN creates the client's TUN interface. If conn is non-nil and has
+// negotiated datagram support, the MTU it reports is mtu capped to
+// conservativeMaxDatagramSize (see its doc comment in forward.go). That
+// MTU is only logged: the interface's own MTU is not changed here.
+func setupTUN(conn quic.Connection, mtu int) (*water.Interface, error) {
+	iface, err := water.New(water.Config{DeviceType: water.TUN})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN interface: %w", err)
+	}
+
+	if conn != nil {
+		if max := datagramMTU(conn); max > 0 && max < mtu {
+			mtu = max
+		}
+	}
+
+	log.Printf("TUN interface %s created with MTU %d", iface.Name(), mtu)
+	return iface, nil
+}
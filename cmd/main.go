@@ -0,0 +1,93 @@
+// Package main is a minimal QUIC echo server used to exercise the
+// transport and TLS layers in isolation from the full StealthQUIC VPN
+// server in package server.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/tlsconf"
+)
+
+func main() {
+	addr := flag.String("addr", "0.0.0.0:4242", "Server listen address")
+	caCert := flag.String("ca-cert", "", "Path to CA certificate for client authentication")
+	flag.Parse()
+
+	tlsManager, err := tlsconf.NewManager(tlsconf.RoleServer, tlsconf.RoleConfig{
+		CA:        *caCert,
+		AutoCerts: true,
+		SkipCA:    *caCert == "",
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up TLS manager: %v", err)
+	}
+
+	listener, err := quic.ListenAddr(*addr, tlsManager.TLSConfig([]string{"quic-echo-example"}), &quic.Config{})
+	if err != nil {
+		log.Fatalf("Failed to start QUIC listener: %v", err)
+	}
+	defer listener.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	var wg sync.WaitGroup
+	for {
+		select {
+		case <-interrupt:
+			wg.Wait()
+			return
+		default:
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				log.Printf("Failed to accept connection: %v", err)
+				continue
+			}
+			wg.Add(1)
+			go handleConnection(conn, &wg)
+		}
+	}
+}
+
+func handleConnection(conn quic.Connection, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer conn.CloseWithError(0, "server closing connection")
+
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Failed to accept stream: %v", err)
+			}
+			return
+		}
+		go echoStream(stream)
+	}
+}
+
+func echoStream(stream quic.Stream) {
+	defer stream.Close()
+	buffer := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buffer)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Stream read error: %v", err)
+			}
+			return
+		}
+		if _, err := stream.Write(buffer[:n]); err != nil {
+			log.Printf("Stream write error: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package fec implements forward error correction codecs (Tetrys, RaptorQ)
+// used to recover TUN packets lost on the QUIC data path without waiting
+// for a retransmission.
+package fec
+
+import "encoding/binary"
+
+// FEC is implemented by every codec in this package. Encode may return
+// additional repair packets alongside (or instead of) the source packet;
+// Decode consumes packets in arrival order and returns every packet it
+// was able to deliver as a result - the source packet itself, any
+// symbols a repair packet let it solve for, or nothing if pkt didn't
+// resolve anything yet.
+type FEC interface {
+	Encode(pkt []byte) [][]byte
+	Decode(pkt []byte) [][]byte
+}
+
+// repairFlag marks the high bit of a framed packet's id as belonging to
+// a repair symbol rather than a source packet. Every codec's header
+// uses the same convention so IsRepair can inspect it without knowing
+// which codec framed the packet.
+const repairFlag = 1 << 31
+
+// Config controls redundancy and window sizing shared by all codecs.
+type Config struct {
+	// MaxRedundancy is the fraction of repair symbols relative to source
+	// symbols, e.g. 0.2 emits one repair packet per five source packets.
+	MaxRedundancy float64
+	// WindowSize bounds how many source symbols a codec keeps buffered
+	// before it is forced to flush the oldest entries. Only the Tetrys
+	// codec reads it; RaptorQ's block size follows MaxRedundancy alone.
+	WindowSize int
+}
+
+// DefaultConfig returns the redundancy/window settings used when a caller
+// does not override them.
+func DefaultConfig() Config {
+	return Config{MaxRedundancy: 0.2, WindowSize: 256}
+}
+
+// IsRepair reports whether a framed packet produced by Encode is a repair
+// symbol rather than a source packet, so callers can treat the two
+// differently (e.g. mirroring only repair symbols across multipath
+// standbys) without reaching into codec internals.
+func IsRepair(pkt []byte) bool {
+	if len(pkt) < 4 {
+		return false
+	}
+	return binary.BigEndian.Uint32(pkt[0:4])&repairFlag != 0
+}
@@ -0,0 +1,78 @@
+package fec
+
+// GF(256) arithmetic under the Reed-Solomon reducing polynomial
+// x^8+x^4+x^3+x^2+1 (0x11d), via precomputed log/antilog tables. Used by
+// the Tetrys codec to combine source symbols with random, nonzero
+// coefficients instead of plain XOR, so a single repair symbol's
+// equation can be solved for exactly one of several candidate unknowns.
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		x = gfMulSlow(x, 0x02)
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+// gfMulSlow multiplies without the log/antilog tables; used only to build
+// them during init.
+func gfMulSlow(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8 && a != 0 && b != 0; i++ {
+		if b&1 != 0 {
+			p ^= a
+		}
+		hiBitSet := a&0x80 != 0
+		a <<= 1
+		if hiBitSet {
+			a ^= 0x1d
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// gfMul multiplies two GF(256) elements.
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfDiv divides a by b in GF(256); b must be nonzero.
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+255-int(gfLog[b]))%255]
+}
+
+// gfAddScaled adds c*src into dst (GF(256) addition is XOR), zero-padding
+// src out to len(dst) if it's shorter.
+func gfAddScaled(dst []byte, src []byte, c byte) {
+	for i := range dst {
+		var b byte
+		if i < len(src) {
+			b = src[i]
+		}
+		dst[i] ^= gfMul(c, b)
+	}
+}
+
+// gfScale multiplies every byte of src by c, returning a new slice.
+func gfScale(src []byte, c byte) []byte {
+	out := make([]byte, len(src))
+	for i, b := range src {
+		out[i] = gfMul(c, b)
+	}
+	return out
+}
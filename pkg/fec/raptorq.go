@@ -0,0 +1,211 @@
+package fec
+
+import "encoding/binary"
+
+// raptorQHeaderSize is the size, in bytes, of the header raptorQHeader
+// marshals to.
+const raptorQHeaderSize = 4 + 2 + 2 + 4
+
+// raptorQHeader is prepended to every packet a raptorQEncoder emits: id
+// is a monotonic sequence number (with repairFlag set for repair
+// symbols), k/n describe the block a repair symbol was built from, and
+// seed is the id of the first source packet in that block.
+type raptorQHeader struct {
+	ID   uint32
+	K    uint16
+	N    uint16
+	Seed uint32
+}
+
+func (h raptorQHeader) marshal() []byte {
+	buf := make([]byte, raptorQHeaderSize)
+	binary.BigEndian.PutUint32(buf[0:4], h.ID)
+	binary.BigEndian.PutUint16(buf[4:6], h.K)
+	binary.BigEndian.PutUint16(buf[6:8], h.N)
+	binary.BigEndian.PutUint32(buf[8:12], h.Seed)
+	return buf
+}
+
+func unmarshalRaptorQHeader(buf []byte) (raptorQHeader, []byte, bool) {
+	if len(buf) < raptorQHeaderSize {
+		return raptorQHeader{}, nil, false
+	}
+	h := raptorQHeader{
+		ID:   binary.BigEndian.Uint32(buf[0:4]),
+		K:    binary.BigEndian.Uint16(buf[4:6]),
+		N:    binary.BigEndian.Uint16(buf[6:8]),
+		Seed: binary.BigEndian.Uint32(buf[8:12]),
+	}
+	return h, buf[raptorQHeaderSize:], true
+}
+
+// raptorQSymbol is one source symbol buffered in the current block.
+type raptorQSymbol struct {
+	id   uint32
+	data []byte
+}
+
+// raptorQEncoder implements a block-based FEC codec: source packets are
+// grouped into fixed-size blocks and each block is followed by one
+// repair symbol (XOR parity over the block) so the decoder can
+// reconstruct a single lost packet per block without requiring a
+// retransmission.
+type raptorQEncoder struct {
+	cfg   Config
+	block []raptorQSymbol
+	id    uint32
+}
+
+func newRaptorQEncoder(cfg Config) *raptorQEncoder {
+	if cfg.MaxRedundancy <= 0 {
+		cfg.MaxRedundancy = DefaultConfig().MaxRedundancy
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = DefaultConfig().WindowSize
+	}
+	return &raptorQEncoder{cfg: cfg}
+}
+
+func (e *raptorQEncoder) blockSize() int {
+	n := int(1 / e.cfg.MaxRedundancy)
+	if n < 2 {
+		n = 2
+	}
+	return n
+}
+
+// Encode buffers pkt into the current block and, once the block is full,
+// emits the source packet followed by one repair symbol for the block.
+func (e *raptorQEncoder) Encode(pkt []byte) [][]byte {
+	id := e.id
+	e.id++
+
+	src := make([]byte, len(pkt))
+	copy(src, pkt)
+	e.block = append(e.block, raptorQSymbol{id: id, data: src})
+
+	hdr := raptorQHeader{ID: id, K: 1, N: 1}
+	out := [][]byte{append(hdr.marshal(), pkt...)}
+
+	if len(e.block) >= e.blockSize() {
+		out = append(out, e.buildRepair())
+		e.block = nil
+	}
+	return out
+}
+
+// buildRepair XORs every symbol in the block into a single parity
+// packet. The per-symbol lengths are carried alongside the header, in
+// block order, so the decoder can trim a recovered symbol back to its
+// true length instead of returning it zero-padded to maxLen.
+func (e *raptorQEncoder) buildRepair() []byte {
+	maxLen := 0
+	lengths := make([]byte, 2*len(e.block))
+	for i, w := range e.block {
+		binary.BigEndian.PutUint16(lengths[2*i:2*i+2], uint16(len(w.data)))
+		if len(w.data) > maxLen {
+			maxLen = len(w.data)
+		}
+	}
+	parity := make([]byte, maxLen)
+	for _, w := range e.block {
+		xorInto(parity, w.data)
+	}
+	hdr := raptorQHeader{
+		ID:   e.block[0].id | repairFlag,
+		K:    uint16(len(e.block)),
+		N:    uint16(len(e.block)),
+		Seed: e.block[0].id,
+	}
+	buf := append(hdr.marshal(), lengths...)
+	return append(buf, parity...)
+}
+
+// Decode is not meaningful for a raptorQEncoder; it exists only so
+// newRaptorQEncoder's return value satisfies the FEC interface, letting
+// NewRaptorQ return both halves of the pair as the same type.
+func (e *raptorQEncoder) Decode(pkt []byte) [][]byte { return nil }
+
+func xorInto(dst, src []byte) {
+	for i, b := range src {
+		dst[i] ^= b
+	}
+}
+
+// raptorQDecoder buffers block symbols until enough have been received
+// to decode the source block, mirroring raptorQEncoder.
+type raptorQDecoder struct {
+	buffered map[uint32][]byte
+}
+
+func newRaptorQDecoder() *raptorQDecoder {
+	return &raptorQDecoder{buffered: make(map[uint32][]byte)}
+}
+
+// Decode accepts a framed source or repair packet and returns every
+// packet that becomes available as a result: the source packet itself
+// (if this was one), or the single packet a repair symbol recovered.
+func (d *raptorQDecoder) Decode(pkt []byte) [][]byte {
+	hdr, payload, ok := unmarshalRaptorQHeader(pkt)
+	if !ok {
+		return nil
+	}
+
+	if hdr.ID&repairFlag == 0 {
+		d.buffered[hdr.ID] = payload
+		return [][]byte{payload}
+	}
+
+	k := int(hdr.K)
+	lengthsSize := 2 * k
+	if len(payload) < lengthsSize {
+		return nil
+	}
+	lengths := payload[:lengthsSize]
+	parity := payload[lengthsSize:]
+
+	missing := uint32(0)
+	missingLen := uint16(0)
+	missingCount := 0
+	for i := 0; i < k; i++ {
+		id := hdr.Seed + uint32(i)
+		if _, have := d.buffered[id]; !have {
+			missing = id
+			missingLen = binary.BigEndian.Uint16(lengths[2*i : 2*i+2])
+			missingCount++
+		}
+	}
+	if missingCount != 1 {
+		return nil
+	}
+
+	recovered := make([]byte, len(parity))
+	copy(recovered, parity)
+	for i := 0; i < k; i++ {
+		id := hdr.Seed + uint32(i)
+		if id == missing {
+			continue
+		}
+		xorInto(recovered, d.buffered[id])
+	}
+	if int(missingLen) <= len(recovered) {
+		recovered = recovered[:missingLen]
+	}
+	d.buffered[missing] = recovered
+	return [][]byte{recovered}
+}
+
+// Encode is not meaningful for a raptorQDecoder; it exists only so
+// newRaptorQDecoder's return value satisfies the FEC interface, letting
+// NewRaptorQ return both halves of the pair as the same type.
+func (d *raptorQDecoder) Encode(pkt []byte) [][]byte { return nil }
+
+// NewRaptorQ returns a matched encoder/decoder pair using cfg.
+func NewRaptorQ(cfg Config) (FEC, FEC) {
+	return newRaptorQEncoder(cfg), newRaptorQDecoder()
+}
+
+// NewTetrys returns a matched encoder/decoder pair using cfg.
+func NewTetrys(cfg Config) (FEC, FEC) {
+	return newTetrysEncoder(cfg), newTetrysDecoder(cfg)
+}
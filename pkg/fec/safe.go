@@ -0,0 +1,25 @@
+package fec
+
+import "sync"
+
+// SafeDecoder wraps a decoder so its Decode method can be called safely
+// from more than one goroutine at once - e.g. a connection's dedicated
+// stream and its datagram receiver decoding into the same codec state.
+// Neither tetrysDecoder nor raptorQDecoder otherwise guards its internal
+// maps against concurrent access.
+type SafeDecoder struct {
+	mu sync.Mutex
+	FEC
+}
+
+// NewSafeDecoder wraps decoder so Decode can be called concurrently.
+func NewSafeDecoder(decoder FEC) *SafeDecoder {
+	return &SafeDecoder{FEC: decoder}
+}
+
+// Decode serializes access to the wrapped decoder's Decode method.
+func (d *SafeDecoder) Decode(pkt []byte) [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.FEC.Decode(pkt)
+}
@@ -0,0 +1,357 @@
+package fec
+
+import (
+	"encoding/binary"
+	"math/rand"
+)
+
+// tetrysHeaderSize is the size, in bytes, of the header tetrysHeader
+// marshals to.
+const tetrysHeaderSize = 4 + 2 + 4 + 2 + 4
+
+// tetrysHeader is prepended to every packet (source or repair) that flows
+// through a tetrysEncoder/tetrysDecoder pair. ID's high bit (repairFlag)
+// marks a repair symbol; source packets set only ID and Length, while
+// repair packets additionally describe the coding window they cover
+// (WindowStart, WindowLen) and the PRNG seed that generated their
+// GF(256) coefficient row, so the decoder can regenerate the exact same
+// row without transmitting it. A repair packet's Length is the padded
+// (longest-in-window) symbol length used to size the GF(256) parity, not
+// any individual source symbol's true length - those travel separately,
+// as a WindowLen-entry length table immediately following this header
+// (see buildRepair/reduceEquation), so a solved symbol can be trimmed
+// back to the length it actually had on the wire.
+type tetrysHeader struct {
+	ID          uint32
+	Length      uint16 // source: true length. repair: padded parity length
+	WindowStart uint32 // first source id in the window (repair only)
+	WindowLen   uint16 // number of source symbols in the window (repair only)
+	Seed        uint32 // coefficient PRNG seed (repair only)
+}
+
+func (h tetrysHeader) marshal() []byte {
+	buf := make([]byte, tetrysHeaderSize)
+	binary.BigEndian.PutUint32(buf[0:4], h.ID)
+	binary.BigEndian.PutUint16(buf[4:6], h.Length)
+	binary.BigEndian.PutUint32(buf[6:10], h.WindowStart)
+	binary.BigEndian.PutUint16(buf[10:12], h.WindowLen)
+	binary.BigEndian.PutUint32(buf[12:16], h.Seed)
+	return buf
+}
+
+func unmarshalTetrysHeader(buf []byte) (tetrysHeader, []byte, bool) {
+	if len(buf) < tetrysHeaderSize {
+		return tetrysHeader{}, nil, false
+	}
+	h := tetrysHeader{
+		ID:          binary.BigEndian.Uint32(buf[0:4]),
+		Length:      binary.BigEndian.Uint16(buf[4:6]),
+		WindowStart: binary.BigEndian.Uint32(buf[6:10]),
+		WindowLen:   binary.BigEndian.Uint16(buf[10:12]),
+		Seed:        binary.BigEndian.Uint32(buf[12:16]),
+	}
+	return h, buf[tetrysHeaderSize:], true
+}
+
+// tetrysCoefficients deterministically regenerates the n nonzero GF(256)
+// coefficients a repair symbol seeded by seed combined its window with,
+// so the encoder never has to transmit them.
+func tetrysCoefficients(seed uint32, n int) []byte {
+	rng := rand.New(rand.NewSource(int64(seed)))
+	coeffs := make([]byte, n)
+	for i := range coeffs {
+		// 1..255: GF(256) coefficients must be nonzero to be invertible.
+		coeffs[i] = byte(rng.Intn(255) + 1)
+	}
+	return coeffs
+}
+
+// tetrysSymbol is one source symbol held in the sliding coding window.
+type tetrysSymbol struct {
+	id   uint32
+	data []byte
+}
+
+// tetrysEncoder implements an on-the-fly, convolutional Tetrys FEC: every
+// 1/redundancy source packets it emits a repair symbol formed as a
+// random linear combination over GF(256) of every symbol currently in
+// the sliding window, letting the decoder solve for any one missing
+// symbol a repair equation still references once every other symbol in
+// that equation is known.
+type tetrysEncoder struct {
+	cfg         Config
+	nextID      uint32
+	sinceRepair int
+	window      []tetrysSymbol
+}
+
+// newTetrysEncoder creates a Tetrys encoder using cfg's redundancy ratio
+// and window size.
+func newTetrysEncoder(cfg Config) *tetrysEncoder {
+	if cfg.MaxRedundancy <= 0 {
+		cfg.MaxRedundancy = DefaultConfig().MaxRedundancy
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = DefaultConfig().WindowSize
+	}
+	return &tetrysEncoder{cfg: cfg}
+}
+
+// Encode appends pkt to the sliding window and returns the framed source
+// packet, plus a trailing repair packet whenever the redundancy ratio
+// calls for one. The window is force-flushed (oldest symbol dropped)
+// once it exceeds cfg.WindowSize, bounding memory regardless of whether
+// the decoder ever acknowledges anything.
+func (e *tetrysEncoder) Encode(pkt []byte) [][]byte {
+	id := e.nextID
+	e.nextID++
+
+	src := make([]byte, len(pkt))
+	copy(src, pkt)
+	e.window = append(e.window, tetrysSymbol{id: id, data: src})
+	if len(e.window) > e.cfg.WindowSize {
+		e.window = e.window[len(e.window)-e.cfg.WindowSize:]
+	}
+
+	hdr := tetrysHeader{ID: id, Length: uint16(len(pkt))}
+	out := [][]byte{append(hdr.marshal(), pkt...)}
+
+	every := int(1 / e.cfg.MaxRedundancy)
+	if every < 1 {
+		every = 1
+	}
+	e.sinceRepair++
+	if e.sinceRepair >= every {
+		e.sinceRepair = 0
+		out = append(out, e.buildRepair(id))
+	}
+	return out
+}
+
+// buildRepair combines every symbol in the window with a random, nonzero
+// GF(256) coefficient into a single repair packet, seeded by the repair
+// packet's own id. The per-symbol lengths are carried alongside the
+// header, in window order, so the decoder can trim a solved symbol back
+// to its true length instead of returning it zero-padded to maxLen.
+func (e *tetrysEncoder) buildRepair(id uint32) []byte {
+	maxLen := 0
+	lengths := make([]byte, 2*len(e.window))
+	for i, w := range e.window {
+		binary.BigEndian.PutUint16(lengths[2*i:2*i+2], uint16(len(w.data)))
+		if len(w.data) > maxLen {
+			maxLen = len(w.data)
+		}
+	}
+	coeffs := tetrysCoefficients(id, len(e.window))
+	parity := make([]byte, maxLen)
+	for i, w := range e.window {
+		gfAddScaled(parity, w.data, coeffs[i])
+	}
+
+	hdr := tetrysHeader{
+		ID:          id | repairFlag,
+		Length:      uint16(maxLen),
+		WindowStart: e.window[0].id,
+		WindowLen:   uint16(len(e.window)),
+		Seed:        id,
+	}
+	buf := append(hdr.marshal(), lengths...)
+	return append(buf, parity...)
+}
+
+// Decode is not meaningful for a tetrysEncoder; it exists only so
+// newTetrysEncoder's return value satisfies the FEC interface, letting
+// NewTetrys return both halves of the pair as the same type.
+func (e *tetrysEncoder) Decode(pkt []byte) [][]byte { return nil }
+
+// tetrysEquation is one repair symbol's linear equation, reduced to only
+// the source ids it still can't account for: every id already buffered
+// has had coeff*symbol subtracted out of rhs and removed from coeffs.
+// lengths records each still-unknown id's true (unpadded) symbol length,
+// so a solved symbol can be trimmed back to it.
+type tetrysEquation struct {
+	coeffs  map[uint32]byte
+	lengths map[uint32]uint16
+	rhs     []byte
+}
+
+// tetrysDecoder mirrors tetrysEncoder on the receive side: it buffers
+// source symbols as they arrive, keeps every repair equation that still
+// has unresolved unknowns, solves any equation that is down to a single
+// unknown by GF(256) division, and propagates each newly solved symbol
+// into every other pending equation that referenced it. Equations that
+// each still hold two or more unknowns are not combined with one another;
+// they are solved only once substitution has left one unknown in them.
+type tetrysDecoder struct {
+	cfg      Config
+	buffered map[uint32][]byte
+	pending  []*tetrysEquation
+}
+
+func newTetrysDecoder(cfg Config) *tetrysDecoder {
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = DefaultConfig().WindowSize
+	}
+	return &tetrysDecoder{cfg: cfg, buffered: make(map[uint32][]byte)}
+}
+
+// Decode accepts a framed source or repair packet and returns every
+// symbol that becomes available as a result: the source packet itself
+// (if this was one), plus any symbols a now-simplified repair equation
+// was able to solve for.
+func (d *tetrysDecoder) Decode(pkt []byte) [][]byte {
+	hdr, payload, ok := unmarshalTetrysHeader(pkt)
+	if !ok {
+		return nil
+	}
+
+	var recovered [][]byte
+	if hdr.ID&repairFlag == 0 {
+		if int(hdr.Length) > len(payload) {
+			return nil
+		}
+		d.learn(hdr.ID, payload[:hdr.Length])
+		recovered = append(recovered, payload[:hdr.Length])
+	} else if eq := d.reduceEquation(hdr, payload); eq != nil {
+		d.pending = append(d.pending, eq)
+	}
+
+	recovered = append(recovered, d.solvePending()...)
+	d.evict()
+	return recovered
+}
+
+// reduceEquation parses the per-symbol length table buildRepair placed
+// after the header, builds the repair symbol's equation, immediately
+// subtracting out every source id already buffered, and regenerates the
+// same coefficient row the encoder used from the repair's seed.
+func (d *tetrysDecoder) reduceEquation(hdr tetrysHeader, payload []byte) *tetrysEquation {
+	n := int(hdr.WindowLen)
+	lengthsSize := 2 * n
+	if len(payload) < lengthsSize {
+		return nil
+	}
+	lengths := payload[:lengthsSize]
+	parity := payload[lengthsSize:]
+
+	coeffs := tetrysCoefficients(hdr.Seed, n)
+	rhs := make([]byte, len(parity))
+	copy(rhs, parity)
+
+	eq := &tetrysEquation{coeffs: make(map[uint32]byte), lengths: make(map[uint32]uint16)}
+	for i := uint32(0); i < uint32(n); i++ {
+		id := hdr.WindowStart + i
+		c := coeffs[i]
+		if known, ok := d.buffered[id]; ok {
+			gfAddScaled(rhs, known, c)
+			continue
+		}
+		eq.coeffs[id] = c
+		eq.lengths[id] = binary.BigEndian.Uint16(lengths[2*i : 2*i+2])
+	}
+	eq.rhs = rhs
+
+	if len(eq.coeffs) == 0 {
+		return nil // every symbol in this window is already known
+	}
+	return eq
+}
+
+// solvePending repeatedly solves any equation down to exactly one
+// remaining unknown, substitutes the result into every other pending
+// equation, and repeats until no further progress is possible.
+func (d *tetrysDecoder) solvePending() [][]byte {
+	var recovered [][]byte
+	for {
+		progressed := false
+		remaining := d.pending[:0]
+		for _, eq := range d.pending {
+			if len(eq.coeffs) != 1 {
+				remaining = append(remaining, eq)
+				continue
+			}
+			var id uint32
+			var c byte
+			for k, v := range eq.coeffs {
+				id, c = k, v
+			}
+			symbol := gfScale(eq.rhs, gfDiv(1, c))
+			if l := eq.lengths[id]; int(l) <= len(symbol) {
+				symbol = symbol[:l]
+			}
+			d.learn(id, symbol)
+			recovered = append(recovered, symbol)
+			progressed = true
+		}
+		d.pending = remaining
+		if !progressed {
+			break
+		}
+		d.substituteKnown()
+	}
+	return recovered
+}
+
+// substituteKnown removes every now-buffered id from every pending
+// equation's coefficient map, folding its contribution into rhs.
+func (d *tetrysDecoder) substituteKnown() {
+	for _, eq := range d.pending {
+		for id, c := range eq.coeffs {
+			known, ok := d.buffered[id]
+			if !ok {
+				continue
+			}
+			gfAddScaled(eq.rhs, known, c)
+			delete(eq.coeffs, id)
+		}
+	}
+}
+
+func (d *tetrysDecoder) learn(id uint32, symbol []byte) {
+	cp := make([]byte, len(symbol))
+	copy(cp, symbol)
+	d.buffered[id] = cp
+}
+
+// evict drops buffered symbols and equations that have aged out of the
+// window, bounding memory once the encoder has moved well past them
+// regardless of out-of-order arrival.
+func (d *tetrysDecoder) evict() {
+	if len(d.buffered) <= d.cfg.WindowSize*2 {
+		return
+	}
+	var maxID uint32
+	for id := range d.buffered {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	horizon := uint32(0)
+	if maxID > uint32(d.cfg.WindowSize) {
+		horizon = maxID - uint32(d.cfg.WindowSize)
+	}
+	for id := range d.buffered {
+		if id < horizon {
+			delete(d.buffered, id)
+		}
+	}
+	remaining := d.pending[:0]
+	for _, eq := range d.pending {
+		stale := true
+		for id := range eq.coeffs {
+			if id >= horizon {
+				stale = false
+				break
+			}
+		}
+		if !stale {
+			remaining = append(remaining, eq)
+		}
+	}
+	d.pending = remaining
+}
+
+// Encode is not meaningful for a tetrysDecoder; it exists only so
+// newTetrysDecoder's return value satisfies the FEC interface, letting
+// NewTetrys return both halves of the pair as the same type.
+func (d *tetrysDecoder) Encode(pkt []byte) [][]byte { return nil }
@@ -0,0 +1,180 @@
+// Package metrics collects the Prometheus counters/histograms exported by
+// every QuicFuscate server binary, and the qlog bookkeeping needed to
+// serve per-connection traces over HTTP.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds every counter/gauge/histogram a server process exports.
+// A single instance is shared across all connections and goroutines.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	AcceptedConnections  prometheus.Counter
+	ActiveStreams        prometheus.Gauge
+	BytesTotal           *prometheus.CounterVec // labels: direction=rx|tx
+	FECRepairSent        prometheus.Counter
+	FECDecodeSuccess     prometheus.Counter
+	FECDecodeFailure     prometheus.Counter
+	EncryptionFailures   prometheus.Counter
+	CipherBytesTotal     *prometheus.CounterVec // labels: cipher, direction=rx|tx
+	ScanPacketsDropped   prometheus.Counter
+	KeepaliveRTT         prometheus.Histogram
+	TLSHandshakeDuration prometheus.Histogram
+
+	mu          sync.Mutex
+	qlogPaths   map[string]string         // connection id -> qlog file path
+	connections map[string]ConnectionInfo // connection id -> debug info
+}
+
+// ConnectionInfo is a snapshot of one active QUIC connection's
+// observability-relevant state, as exposed by /debug/connections.
+type ConnectionInfo struct {
+	ID              string `json:"id"`
+	RemoteAddr      string `json:"remote_addr"`
+	ALPN            string `json:"alpn"`
+	StealthProfile  string `json:"stealth_profile,omitempty"`
+	CongestionState string `json:"congestion_state"`
+	DatagramMTU     int    `json:"datagram_mtu"`
+}
+
+// New creates a Metrics instance and registers every collector with a
+// fresh registry so multiple server instances in the same process (e.g.
+// in tests) don't collide on global registration.
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		Registry: registry,
+		AcceptedConnections: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_accepted_connections_total",
+			Help: "Total number of QUIC connections accepted.",
+		}),
+		ActiveStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "quicfuscate_active_streams",
+			Help: "Number of QUIC streams currently open.",
+		}),
+		BytesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quicfuscate_bytes_total",
+			Help: "Total bytes transferred, by direction.",
+		}, []string{"direction"}),
+		FECRepairSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_fec_repair_sent_total",
+			Help: "Total FEC repair symbols sent.",
+		}),
+		FECDecodeSuccess: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_fec_decode_success_total",
+			Help: "Total packets successfully recovered by FEC.",
+		}),
+		FECDecodeFailure: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_fec_decode_failure_total",
+			Help: "Total packets FEC was unable to recover.",
+		}),
+		EncryptionFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_encryption_failures_total",
+			Help: "Total PDUs dropped due to an encryption or decryption failure.",
+		}),
+		CipherBytesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quicfuscate_cipher_bytes_total",
+			Help: "Bytes processed by the outer encryption layer, by cipher and direction.",
+		}, []string{"cipher", "direction"}),
+		ScanPacketsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "quicfuscate_scan_packets_dropped_total",
+			Help: "Total UDP payloads dropped because they did not parse as a real QUIC Initial packet.",
+		}),
+		KeepaliveRTT: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "quicfuscate_keepalive_rtt_seconds",
+			Help:    "Round-trip time observed by the keepalive ticker.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		TLSHandshakeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "quicfuscate_tls_handshake_duration_seconds",
+			Help:    "Time spent completing the TLS handshake.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		qlogPaths:   make(map[string]string),
+		connections: make(map[string]ConnectionInfo),
+	}
+
+	registry.MustRegister(
+		m.AcceptedConnections,
+		m.ActiveStreams,
+		m.BytesTotal,
+		m.FECRepairSent,
+		m.FECDecodeSuccess,
+		m.FECDecodeFailure,
+		m.EncryptionFailures,
+		m.CipherBytesTotal,
+		m.ScanPacketsDropped,
+		m.KeepaliveRTT,
+		m.TLSHandshakeDuration,
+	)
+	return m
+}
+
+// AddBytes records n bytes transferred in the given direction ("rx" or
+// "tx").
+func (m *Metrics) AddBytes(direction string, n int) {
+	m.BytesTotal.WithLabelValues(direction).Add(float64(n))
+}
+
+// RegisterQlogPath records where a connection's qlog file lives so
+// /qlog/{conn_id} can find it.
+func (m *Metrics) RegisterQlogPath(connID, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.qlogPaths[connID] = path
+}
+
+// QlogPath returns the qlog file path registered for connID, if any.
+func (m *Metrics) QlogPath(connID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path, ok := m.qlogPaths[connID]
+	return path, ok
+}
+
+// RegisterConnection records info for an active connection so it shows up
+// in Connections until UnregisterConnection is called for the same ID.
+func (m *Metrics) RegisterConnection(info ConnectionInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connections[info.ID] = info
+}
+
+// UnregisterConnection removes a connection recorded by RegisterConnection,
+// e.g. once it has closed.
+func (m *Metrics) UnregisterConnection(connID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.connections, connID)
+}
+
+// UpdateDatagramMTU updates the DatagramMTU reported for connID by
+// /debug/connections, e.g. as path MTU discovery confirms a new size. It
+// is a no-op if connID is not currently registered.
+func (m *Metrics) UpdateDatagramMTU(connID string, mtu int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info, ok := m.connections[connID]
+	if !ok {
+		return
+	}
+	info.DatagramMTU = mtu
+	m.connections[connID] = info
+}
+
+// Connections returns a snapshot of every currently registered connection.
+func (m *Metrics) Connections() []ConnectionInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conns := make([]ConnectionInfo, 0, len(m.connections))
+	for _, info := range m.connections {
+		conns = append(conns, info)
+	}
+	return conns
+}
@@ -0,0 +1,33 @@
+package multipath
+
+import "net"
+
+// LocalAddrs returns one usable local unicast IP per up, non-loopback
+// network interface — the candidate egress paths (Wi-Fi, cellular,
+// ethernet) a client can dial the server from.
+func LocalAddrs() ([]net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range ifaceAddrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addrs = append(addrs, ipNet.IP)
+			break
+		}
+	}
+	return addrs, nil
+}
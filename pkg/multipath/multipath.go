@@ -0,0 +1,220 @@
+// Package multipath tracks a set of QUIC connections dialed over
+// different local network paths (Wi-Fi, cellular, ethernet) and selects
+// which one should carry traffic, so a VPN client can fail over to a
+// standby path when its active interface goes down.
+package multipath
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+// Mode selects how a Manager picks the active path among its alive
+// candidates.
+type Mode string
+
+const (
+	// ModeFailover keeps using the first alive path in the order paths
+	// were added, falling over to the next only once it goes down.
+	ModeFailover Mode = "failover"
+	// ModeLatency always selects the alive path with the lowest measured
+	// RTT.
+	ModeLatency Mode = "latency"
+	// ModeRoundRobin rotates the active path on every call to Primary.
+	ModeRoundRobin Mode = "round-robin"
+)
+
+// Path is one candidate route to the server: a QUIC connection dialed
+// from a specific local address.
+type Path struct {
+	Name      string
+	LocalAddr net.Addr
+	Conn      quic.Connection
+
+	mu    sync.Mutex
+	rtt   time.Duration
+	alive bool
+}
+
+// NewPath wraps a dialed connection as a named multipath candidate.
+func NewPath(name string, localAddr net.Addr, conn quic.Connection) *Path {
+	return &Path{Name: name, LocalAddr: localAddr, Conn: conn, alive: true}
+}
+
+// RTT returns the path's most recently measured round-trip time.
+func (p *Path) RTT() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rtt
+}
+
+// Alive reports whether the path last responded to a probe.
+func (p *Path) Alive() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.alive
+}
+
+func (p *Path) setRTT(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rtt = d
+	p.alive = true
+}
+
+func (p *Path) markDown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.alive = false
+}
+
+// Manager tracks every dialed Path and selects which one is primary
+// according to Mode.
+type Manager struct {
+	Mode Mode
+
+	mu     sync.Mutex
+	paths  []*Path
+	rrNext int
+}
+
+// NewManager creates a Manager that selects among its paths using mode,
+// defaulting to ModeFailover when mode is empty.
+func NewManager(mode Mode) *Manager {
+	if mode == "" {
+		mode = ModeFailover
+	}
+	return &Manager{Mode: mode}
+}
+
+// Add registers a dialed path with the manager.
+func (m *Manager) Add(p *Path) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paths = append(m.paths, p)
+}
+
+// Paths returns every registered path.
+func (m *Manager) Paths() []*Path {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Path, len(m.paths))
+	copy(out, m.paths)
+	return out
+}
+
+// Primary returns the path that should currently carry traffic, or nil
+// if every path is down.
+func (m *Manager) Primary() *Path {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.Mode {
+	case ModeLatency:
+		var best *Path
+		for _, p := range m.paths {
+			if !p.Alive() {
+				continue
+			}
+			if best == nil || p.RTT() < best.RTT() {
+				best = p
+			}
+		}
+		return best
+	case ModeRoundRobin:
+		n := len(m.paths)
+		for i := 0; i < n; i++ {
+			p := m.paths[(m.rrNext+i)%n]
+			if p.Alive() {
+				m.rrNext = (m.rrNext + i + 1) % n
+				return p
+			}
+		}
+		return nil
+	default: // ModeFailover
+		for _, p := range m.paths {
+			if p.Alive() {
+				return p
+			}
+		}
+		return nil
+	}
+}
+
+// Standbys returns every alive path other than the current primary, for
+// repair-symbol duplication.
+func (m *Manager) Standbys() []*Path {
+	primary := m.Primary()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Path
+	for _, p := range m.paths {
+		if p == primary || !p.Alive() {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// Probe measures round-trip time on every path by opening a short stream
+// and exchanging a keepalive ping, marking unresponsive paths down.
+func (m *Manager) Probe(ctx context.Context, timeout time.Duration) {
+	for _, p := range m.Paths() {
+		start := time.Now()
+		if err := probeOne(ctx, p.Conn, timeout); err != nil {
+			p.markDown()
+			continue
+		}
+		p.setRTT(time.Since(start))
+	}
+}
+
+func probeOne(ctx context.Context, conn quic.Connection, timeout time.Duration) error {
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+	// DrainTimeout is bounded by the probe's own timeout rather than
+	// safestream's default: a probe that already got its answer shouldn't
+	// block for several extra seconds waiting out a peer that never
+	// closes its side of a ping/pong stream.
+	defer (&safestream.Closer{Stream: stream, DrainTimeout: timeout}).Close()
+
+	stream.SetDeadline(time.Now().Add(timeout))
+	if _, err := stream.Write([]byte("keepalive")); err != nil {
+		return err
+	}
+	buf := make([]byte, 16)
+	n, err := stream.Read(buf)
+	if err != nil {
+		return err
+	}
+	if string(buf[:n]) != "alive" {
+		return fmt.Errorf("unexpected probe response: %q", buf[:n])
+	}
+	return nil
+}
+
+// Watch re-probes every path on interval until ctx is cancelled, so
+// Primary reflects up-to-date RTTs and liveness.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Probe(ctx, interval/2)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package multipath
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// dialLoopbackPath starts a QUIC listener on 127.0.0.1 that answers
+// "keepalive" probes the way the real server's control stream does,
+// dials it, and returns the dialed connection plus a func that severs
+// every connection the listener has accepted so far and stops it
+// accepting new ones - simulating that path's server-side network
+// disappearing mid-transfer, not just going quiet.
+func dialLoopbackPath(t *testing.T) (conn quic.Connection, kill func()) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "multipath-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"multipath-test"},
+	}
+
+	listener, err := quic.ListenAddr("127.0.0.1:0", tlsConf, nil)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var mu sync.Mutex
+	var serverConns []quic.Connection
+
+	go func() {
+		for {
+			serverConn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			serverConns = append(serverConns, serverConn)
+			mu.Unlock()
+
+			go func() {
+				for {
+					stream, err := serverConn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						buf := make([]byte, 32)
+						n, err := stream.Read(buf)
+						if err != nil {
+							return
+						}
+						if string(buf[:n]) == "keepalive" {
+							stream.Write([]byte("alive"))
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	clientConn, err := quic.DialAddr(context.Background(), listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"multipath-test"},
+	}, nil)
+	if err != nil {
+		listener.Close()
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	kill = func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range serverConns {
+			c.CloseWithError(0, "simulated path death")
+		}
+	}
+	return clientConn, kill
+}
+
+// TestManagerFailoverOnPathDeath drives a Manager over two real loopback
+// QUIC connections, confirms both paths probe alive and the failover
+// path is primary, then kills the primary path's listener mid-transfer
+// and confirms Probe marks it down and Primary fails over to the
+// surviving path.
+func TestManagerFailoverOnPathDeath(t *testing.T) {
+	connA, killA := dialLoopbackPath(t)
+	defer killA()
+	defer connA.CloseWithError(0, "test done")
+
+	connB, killB := dialLoopbackPath(t)
+	defer killB()
+	defer connB.CloseWithError(0, "test done")
+
+	m := NewManager(ModeFailover)
+	pathA := NewPath("a", &net.UDPAddr{}, connA)
+	pathB := NewPath("b", &net.UDPAddr{}, connB)
+	m.Add(pathA)
+	m.Add(pathB)
+
+	m.Probe(context.Background(), time.Second)
+	if !pathA.Alive() || !pathB.Alive() {
+		t.Fatalf("expected both paths alive after initial probe, got a=%v b=%v", pathA.Alive(), pathB.Alive())
+	}
+	if primary := m.Primary(); primary != pathA {
+		t.Fatalf("expected path a primary before failover, got %v", primary)
+	}
+
+	// Simulate path a's network disappearing mid-transfer.
+	killA()
+
+	m.Probe(context.Background(), time.Second)
+	if pathA.Alive() {
+		t.Fatal("expected path a to be marked down after its listener died")
+	}
+	if !pathB.Alive() {
+		t.Fatal("expected path b to remain alive")
+	}
+	if primary := m.Primary(); primary != pathB {
+		t.Fatalf("expected failover to path b, got %v", primary)
+	}
+}
+
+// TestManagerRoundRobinSkipsDeadPath confirms ModeRoundRobin rotates
+// only among alive paths, skipping one marked down by a failed probe.
+func TestManagerRoundRobinSkipsDeadPath(t *testing.T) {
+	connA, killA := dialLoopbackPath(t)
+	defer killA()
+	defer connA.CloseWithError(0, "test done")
+
+	connB, killB := dialLoopbackPath(t)
+	defer killB()
+	defer connB.CloseWithError(0, "test done")
+
+	m := NewManager(ModeRoundRobin)
+	pathA := NewPath("a", &net.UDPAddr{}, connA)
+	pathB := NewPath("b", &net.UDPAddr{}, connB)
+	m.Add(pathA)
+	m.Add(pathB)
+
+	killA()
+	m.Probe(context.Background(), time.Second)
+	if pathA.Alive() {
+		t.Fatal("expected path a to be marked down")
+	}
+
+	for i := 0; i < 4; i++ {
+		if primary := m.Primary(); primary != pathB {
+			t.Fatalf("round %d: expected only surviving path b, got %v", i, primary)
+		}
+	}
+}
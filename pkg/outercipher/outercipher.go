@@ -0,0 +1,297 @@
+// Package outercipher implements the optional outer-obfuscation AEAD layer
+// applied to TUN PDUs before they are handed to the QUIC stream or
+// datagram path. It exists for operators who do not trust the QUIC TLS
+// session itself - for example a pinned-certificate deployment sitting
+// behind a MITM proxy - and is off by default, since QUIC's own
+// encryption is otherwise sufficient on its own.
+package outercipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// counterSize is the number of bytes of explicit per-packet counter
+// carried in front of every PDU, in place of a full transmitted nonce.
+const counterSize = 8
+
+// Config controls the optional outer AEAD layer. It is only applied when
+// Enabled is true and Key holds a pre-shared key distinct from the QUIC
+// session's own TLS keys.
+type Config struct {
+	Enabled   bool   `yaml:"enabled"`
+	Algorithm string `yaml:"algorithm"` // "aes-256-gcm", "chacha20-poly1305" or "xchacha20-poly1305"
+	Key       string `yaml:"key"`       // base64-encoded pre-shared key
+
+	// RotateAfterPackets and RotateAfterSeconds bound how long a single
+	// epoch's key and nonce salt may be used for outgoing PDUs before a
+	// rotation is due. Either may be zero to disable that trigger.
+	RotateAfterPackets uint64 `yaml:"rotate_after_packets"`
+	RotateAfterSeconds int    `yaml:"rotate_after_seconds"`
+}
+
+// direction holds one side's independent AEAD state: the epoch-derived
+// key, the salt that fills the high-order bytes of every nonce, and the
+// low-order counter that makes each nonce unique within the epoch.
+type direction struct {
+	epoch   uint32
+	aead    cipher.AEAD
+	salt    []byte
+	counter uint64
+	sent    uint64
+	since   time.Time
+}
+
+// Cipher applies the outer AEAD to PDUs using a deterministic per-packet
+// nonce - a connection-scoped salt plus a monotonic counter - rather than
+// a fresh random nonce per call, so no RNG call (and no risk of a
+// transient RNG failure) is needed on the hot path. Only the 8-byte
+// counter travels with each PDU; the salt is exchanged once, via
+// HandshakeMessage/ApplyPeerHandshake, and again on every rotation.
+//
+// Send and receive state are tracked independently, since this side's
+// outgoing epoch/salt/counter have nothing to do with the peer's.
+type Cipher struct {
+	algorithm     string
+	baseKey       []byte
+	rotatePackets uint64
+	rotateAfter   time.Duration
+
+	mu   sync.Mutex
+	send direction
+	recv direction
+}
+
+// New builds a Cipher from cfg. It is only meaningful to call when
+// cfg.Enabled is true.
+func New(cfg Config) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("outercipher: invalid key: %w", err)
+	}
+
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = "chacha20-poly1305"
+	}
+
+	c := &Cipher{
+		algorithm:     algorithm,
+		baseKey:       key,
+		rotatePackets: cfg.RotateAfterPackets,
+		rotateAfter:   time.Duration(cfg.RotateAfterSeconds) * time.Second,
+	}
+
+	sendAEAD, err := deriveAEAD(key, algorithm, 0)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, sendAEAD.NonceSize()-counterSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("outercipher: failed to generate nonce salt: %w", err)
+	}
+	c.send = direction{aead: sendAEAD, salt: salt, since: time.Now()}
+
+	recvAEAD, err := deriveAEAD(key, algorithm, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.recv = direction{aead: recvAEAD}
+
+	return c, nil
+}
+
+// Algorithm returns the configured AEAD algorithm name, e.g. for labeling
+// metrics.
+func (c *Cipher) Algorithm() string {
+	return c.algorithm
+}
+
+// HandshakeMessage returns the epoch and salt this side currently sends
+// with, wire-formatted for the peer's ApplyPeerHandshake. It must be sent
+// to the peer once at connection start, and again after every Rotate.
+func (c *Cipher) HandshakeMessage() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return encodeHandshake(c.send.epoch, c.send.salt)
+}
+
+// ApplyPeerHandshake applies a handshake message received from the peer,
+// arming this Cipher to decrypt whatever the peer sends under that
+// epoch and salt.
+func (c *Cipher) ApplyPeerHandshake(msg []byte) error {
+	epoch, salt, err := decodeHandshake(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	aead := c.recv.aead
+	if epoch != c.recv.epoch || aead == nil {
+		aead, err = deriveAEAD(c.baseKey, c.algorithm, epoch)
+		if err != nil {
+			return err
+		}
+	}
+	if len(salt) != aead.NonceSize()-counterSize {
+		return fmt.Errorf("outercipher: unexpected salt length %d", len(salt))
+	}
+	c.recv = direction{epoch: epoch, aead: aead, salt: salt}
+	return nil
+}
+
+// Seal encrypts plaintext under this side's current send epoch, returning
+// an 8-byte counter followed by the ciphertext. The counter takes the
+// place of a full transmitted nonce - the rest of the nonce is the salt
+// already known to the peer - saving several bytes per packet.
+func (c *Cipher) Seal(plaintext []byte) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	nonce := make([]byte, c.send.aead.NonceSize())
+	copy(nonce, c.send.salt)
+	binary.BigEndian.PutUint64(nonce[len(c.send.salt):], c.send.counter)
+
+	out := make([]byte, counterSize, counterSize+len(plaintext)+c.send.aead.Overhead())
+	binary.BigEndian.PutUint64(out, c.send.counter)
+	out = c.send.aead.Seal(out, nonce, plaintext, nil)
+
+	c.send.counter++
+	c.send.sent++
+	return out
+}
+
+// Open reverses Seal, decrypting a PDU received from the peer. It returns
+// an error, rather than crashing the process, on any failure - a
+// malformed PDU, an unrecognized counter, or an authentication failure -
+// so a single bad packet can't take down the whole daemon.
+func (c *Cipher) Open(pdu []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.recv.salt == nil {
+		return nil, errors.New("outercipher: peer salt not negotiated yet")
+	}
+	if len(pdu) < counterSize {
+		return nil, errors.New("outercipher: PDU shorter than the nonce counter")
+	}
+
+	nonce := make([]byte, c.recv.aead.NonceSize())
+	copy(nonce, c.recv.salt)
+	copy(nonce[len(c.recv.salt):], pdu[:counterSize])
+
+	plaintext, err := c.recv.aead.Open(nil, nonce, pdu[counterSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("outercipher: decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation reports whether this side's outgoing epoch has carried
+// more packets, or lived longer, than the configured rotation thresholds
+// allow.
+func (c *Cipher) NeedsRotation() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rotatePackets > 0 && c.send.sent >= c.rotatePackets {
+		return true
+	}
+	if c.rotateAfter > 0 && time.Since(c.send.since) >= c.rotateAfter {
+		return true
+	}
+	return false
+}
+
+// Rotate advances this side's outgoing epoch to a freshly derived key and
+// a freshly generated salt, resetting the packet counter. It returns a
+// handshake message that must be delivered to the peer - e.g. over a
+// small control stream - so it can keep decrypting with ApplyPeerHandshake.
+func (c *Cipher) Rotate() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	epoch := c.send.epoch + 1
+	aead, err := deriveAEAD(c.baseKey, c.algorithm, epoch)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, aead.NonceSize()-counterSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("outercipher: failed to generate rotation salt: %w", err)
+	}
+	c.send = direction{epoch: epoch, aead: aead, salt: salt, since: time.Now()}
+
+	return encodeHandshake(epoch, salt), nil
+}
+
+// encodeHandshake wire-formats an epoch and salt as a 4-byte big-endian
+// epoch followed by the raw salt bytes.
+func encodeHandshake(epoch uint32, salt []byte) []byte {
+	msg := make([]byte, 4, 4+len(salt))
+	binary.BigEndian.PutUint32(msg, epoch)
+	return append(msg, salt...)
+}
+
+func decodeHandshake(msg []byte) (uint32, []byte, error) {
+	if len(msg) < 4 {
+		return 0, nil, errors.New("outercipher: handshake message too short")
+	}
+	return binary.BigEndian.Uint32(msg[:4]), msg[4:], nil
+}
+
+// deriveAEAD derives an epoch-scoped AEAD key from baseKey via HKDF and
+// constructs the configured algorithm's cipher.AEAD from it. Each epoch
+// gets an independent key so a rotation is a real re-key, not just a new
+// nonce salt under the same key.
+func deriveAEAD(baseKey []byte, algorithm string, epoch uint32) (cipher.AEAD, error) {
+	info := []byte(fmt.Sprintf("quicfuscate-outercipher-epoch-%d", epoch))
+
+	switch algorithm {
+	case "aes-256-gcm":
+		key, err := hkdfExpand(baseKey, info, 32)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("outercipher: failed to create AES cipher: %w", err)
+		}
+		return cipher.NewGCM(block)
+	case "", "chacha20-poly1305":
+		key, err := hkdfExpand(baseKey, info, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		return chacha20poly1305.New(key)
+	case "xchacha20-poly1305":
+		key, err := hkdfExpand(baseKey, info, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("outercipher: unknown algorithm %q", algorithm)
+	}
+}
+
+func hkdfExpand(secret, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), out); err != nil {
+		return nil, fmt.Errorf("outercipher: key derivation failed: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,176 @@
+package quicsniff
+
+import "fmt"
+
+const (
+	handshakeTypeClientHello = 0x01
+
+	extSNI  = 0x0000
+	extALPN = 0x0010
+
+	sniHostName = 0x00
+)
+
+// parseClientHello parses a TLS Handshake message as the ClientHello it
+// must be (QUIC only ever carries a ClientHello first in the client's
+// Initial CRYPTO stream), extracting the SNI and ALPN extensions.
+func parseClientHello(data []byte) (*ClientHelloInfo, error) {
+	r := &reader{buf: data}
+
+	msgType, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated handshake message: %w", err)
+	}
+	if msgType != handshakeTypeClientHello {
+		return nil, fmt.Errorf("expected ClientHello, got handshake type %#x", msgType)
+	}
+	msgLen, err := r.readN(3)
+	if err != nil {
+		return nil, fmt.Errorf("truncated handshake length: %w", err)
+	}
+	bodyLen := int(msgLen[0])<<16 | int(msgLen[1])<<8 | int(msgLen[2])
+	if r.pos+bodyLen > len(data) {
+		return nil, fmt.Errorf("handshake length %d exceeds CRYPTO data", bodyLen)
+	}
+
+	if _, err := r.readN(2); err != nil { // legacy_version
+		return nil, fmt.Errorf("truncated client_version: %w", err)
+	}
+	if _, err := r.readN(32); err != nil { // random
+		return nil, fmt.Errorf("truncated random: %w", err)
+	}
+
+	sessionIDLen, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated session_id length: %w", err)
+	}
+	if _, err := r.readN(int(sessionIDLen)); err != nil {
+		return nil, fmt.Errorf("truncated session_id: %w", err)
+	}
+
+	cipherSuitesLen, err := r.readUint16()
+	if err != nil {
+		return nil, fmt.Errorf("truncated cipher_suites length: %w", err)
+	}
+	if _, err := r.readN(int(cipherSuitesLen)); err != nil {
+		return nil, fmt.Errorf("truncated cipher_suites: %w", err)
+	}
+
+	compressionLen, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated compression_methods length: %w", err)
+	}
+	if _, err := r.readN(int(compressionLen)); err != nil {
+		return nil, fmt.Errorf("truncated compression_methods: %w", err)
+	}
+
+	info := &ClientHelloInfo{}
+	if r.pos >= len(data) {
+		// No extensions block at all is legal TLS, if unusual for a
+		// browser; there's just nothing more to extract.
+		return info, nil
+	}
+
+	extensionsLen, err := r.readUint16()
+	if err != nil {
+		return nil, fmt.Errorf("truncated extensions length: %w", err)
+	}
+	extensionsEnd := r.pos + int(extensionsLen)
+	if extensionsEnd > len(data) {
+		return nil, fmt.Errorf("extensions length %d exceeds ClientHello", extensionsLen)
+	}
+
+	for r.pos < extensionsEnd {
+		extType, err := r.readUint16()
+		if err != nil {
+			return nil, fmt.Errorf("truncated extension type: %w", err)
+		}
+		extLen, err := r.readUint16()
+		if err != nil {
+			return nil, fmt.Errorf("truncated extension length: %w", err)
+		}
+		extData, err := r.readN(int(extLen))
+		if err != nil {
+			return nil, fmt.Errorf("truncated extension data: %w", err)
+		}
+
+		switch extType {
+		case extSNI:
+			name, err := parseSNIExtension(extData)
+			if err != nil {
+				return nil, fmt.Errorf("malformed server_name extension: %w", err)
+			}
+			info.SNI = name
+		case extALPN:
+			protocols, err := parseALPNExtension(extData)
+			if err != nil {
+				return nil, fmt.Errorf("malformed ALPN extension: %w", err)
+			}
+			info.ALPN = protocols
+		}
+	}
+
+	return info, nil
+}
+
+// parseSNIExtension extracts the host_name entry from a server_name
+// extension's ServerNameList (RFC 6066 §3). Only host_name is defined by
+// the TLS registry; any other entry type is skipped.
+func parseSNIExtension(data []byte) (string, error) {
+	r := &reader{buf: data}
+	listLen, err := r.readUint16()
+	if err != nil {
+		return "", fmt.Errorf("truncated server_name_list length: %w", err)
+	}
+	end := r.pos + int(listLen)
+	if end > len(data) {
+		return "", fmt.Errorf("server_name_list length exceeds extension")
+	}
+
+	for r.pos < end {
+		nameType, err := r.readByte()
+		if err != nil {
+			return "", fmt.Errorf("truncated NameType: %w", err)
+		}
+		nameLen, err := r.readUint16()
+		if err != nil {
+			return "", fmt.Errorf("truncated HostName length: %w", err)
+		}
+		name, err := r.readN(int(nameLen))
+		if err != nil {
+			return "", fmt.Errorf("truncated HostName: %w", err)
+		}
+		if nameType == sniHostName {
+			return string(name), nil
+		}
+	}
+	return "", nil
+}
+
+// parseALPNExtension extracts the ProtocolNameList from an ALPN
+// extension (RFC 7301 §3.1).
+func parseALPNExtension(data []byte) ([]string, error) {
+	r := &reader{buf: data}
+	listLen, err := r.readUint16()
+	if err != nil {
+		return nil, fmt.Errorf("truncated ProtocolNameList length: %w", err)
+	}
+	end := r.pos + int(listLen)
+	if end > len(data) {
+		return nil, fmt.Errorf("ProtocolNameList length exceeds extension")
+	}
+
+	var protocols []string
+	for r.pos < end {
+		protoLen, err := r.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("truncated ProtocolName length: %w", err)
+		}
+		proto, err := r.readN(int(protoLen))
+		if err != nil {
+			return nil, fmt.Errorf("truncated ProtocolName: %w", err)
+		}
+		protocols = append(protocols, string(proto))
+	}
+	return protocols, nil
+}
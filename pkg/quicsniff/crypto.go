@@ -0,0 +1,162 @@
+package quicsniff
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// initialSalt is the per-version public salt HKDF-Extract mixes with the
+// destination connection ID to derive the Initial secret (RFC 9001
+// §5.2, RFC 9369 §3.3.1). It is not secret - it exists only so an
+// observer can't trivially decrypt every QUIC version's Initial traffic
+// with one fixed key, not to hide anything from this package.
+var initialSalt = map[uint32][]byte{
+	versionQUICv1: mustHex("38762cf7f55934b34d179ae6a4c80cadccbb7f0a"),
+	versionQUICv2: mustHex("0dede3def700a6db819381be6e269dcbf9bd2ed9"),
+}
+
+// mustHex decodes a hex literal into the salt bytes derived from it,
+// panicking rather than silently truncating or zeroing a bad nibble -
+// initialSalt is a correctness-critical constant, not input, so a typo
+// here should fail loudly at package init instead of making every real
+// Initial packet fail AEAD and get misclassified as scan noise.
+func mustHex(s string) []byte {
+	if len(s)%2 != 0 {
+		panic(fmt.Sprintf("quicsniff: mustHex: odd-length hex literal %q", s))
+	}
+	b := make([]byte, len(s)/2)
+	for i := range b {
+		hi, ok1 := hexNibble(s[2*i])
+		lo, ok2 := hexNibble(s[2*i+1])
+		if !ok1 || !ok2 {
+			panic(fmt.Sprintf("quicsniff: mustHex: invalid hex literal %q", s))
+		}
+		b[i] = hi<<4 | lo
+	}
+	return b
+}
+
+func hexNibble(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// initialLabels are the HKDF-Expand-Label labels used to derive an
+// Initial secret's key, IV and header protection key. QUIC v2 uses
+// distinct labels from v1 (RFC 9369 §3.3.2) so a v2 Initial can't be
+// decrypted with a v1-derived key even given the same secret.
+type initialLabels struct {
+	key, iv, hp string
+}
+
+var labelsByVersion = map[uint32]initialLabels{
+	versionQUICv1: {key: "quic key", iv: "quic iv", hp: "quic hp"},
+	versionQUICv2: {key: "quicv2 key", iv: "quicv2 iv", hp: "quicv2 hp"},
+}
+
+// decryptInitial removes header protection from and decrypts the
+// Initial packet in pkt (headerLen bytes of unprotected header followed
+// by the protected packet number and payload), deriving keys from dcid
+// per version. It returns the decrypted frame payload.
+func decryptInitial(pkt []byte, headerLen int, dcid []byte, version uint32) ([]byte, error) {
+	labels := labelsByVersion[version]
+
+	initialSecret := hkdfExtract(initialSalt[version], dcid)
+	clientSecret := hkdfExpandLabel(initialSecret, "client in", nil, sha256.Size)
+
+	key := hkdfExpandLabel(clientSecret, labels.key, nil, 16)
+	iv := hkdfExpandLabel(clientSecret, labels.iv, nil, 12)
+	hp := hkdfExpandLabel(clientSecret, labels.hp, nil, 16)
+
+	block, err := aes.NewCipher(hp)
+	if err != nil {
+		return nil, fmt.Errorf("header protection cipher: %w", err)
+	}
+
+	// The packet number field is assumed to be up to 4 bytes long for
+	// sampling purposes regardless of its real length (RFC 9001
+	// §5.4.2): the sample starts 4 bytes after where the packet number
+	// would begin.
+	sampleOffset := headerLen + 4
+	if sampleOffset+16 > len(pkt) {
+		return nil, fmt.Errorf("packet too short to sample header protection")
+	}
+	mask := make([]byte, 16)
+	block.Encrypt(mask, pkt[sampleOffset:sampleOffset+16])
+
+	header := make([]byte, len(pkt))
+	copy(header, pkt)
+	header[0] ^= mask[0] & 0x0f // long header: low 4 bits carry the PN length
+	pnLen := int(header[0]&0x03) + 1
+
+	for i := 0; i < pnLen; i++ {
+		header[headerLen+i] ^= mask[1+i]
+	}
+
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		pn = pn<<8 | uint64(header[headerLen+i])
+	}
+
+	nonce := make([]byte, len(iv))
+	copy(nonce, iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+
+	aeadKey, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("AEAD cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(aeadKey)
+	if err != nil {
+		return nil, fmt.Errorf("AEAD mode: %w", err)
+	}
+
+	associatedData := header[:headerLen+pnLen]
+	ciphertext := header[headerLen+pnLen:]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("AEAD decrypt failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+// hkdfExtract is HKDF-Extract (RFC 5869 §2.2) with SHA-256, the hash QUIC
+// v1 and v2 both use for Initial secrets regardless of the cipher suite
+// eventually negotiated.
+func hkdfExtract(salt, ikm []byte) []byte {
+	return hkdf.Extract(sha256.New, ikm, salt)
+}
+
+// hkdfExpandLabel implements TLS 1.3's HKDF-Expand-Label (RFC 8446
+// §7.1), which QUIC reuses verbatim for key derivation (RFC 9001 §5.1)
+// just with its own labels.
+func hkdfExpandLabel(secret []byte, label string, context []byte, length int) []byte {
+	fullLabel := "tls13 " + label
+
+	info := make([]byte, 0, 2+1+len(fullLabel)+1+len(context))
+	info = append(info, byte(length>>8), byte(length))
+	info = append(info, byte(len(fullLabel)))
+	info = append(info, fullLabel...)
+	info = append(info, byte(len(context)))
+	info = append(info, context...)
+
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(sha256.New, secret, info).Read(out); err != nil {
+		panic("quicsniff: hkdf expand: " + err.Error())
+	}
+	return out
+}
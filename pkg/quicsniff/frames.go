@@ -0,0 +1,156 @@
+package quicsniff
+
+import "fmt"
+
+// QUIC frame type codes this package understands well enough to skip or
+// extract (RFC 9000 §19). Anything else is unexpected in a real client's
+// Initial packet and is treated as a parse failure rather than guessed
+// at, the same way a malformed packet is.
+const (
+	frameTypePadding          = 0x00
+	frameTypePing             = 0x01
+	frameTypeAck              = 0x02
+	frameTypeAckECN           = 0x03
+	frameTypeCrypto           = 0x06
+	frameTypeConnectionClose  = 0x1c
+	frameTypeConnectionCloseApp = 0x1d
+)
+
+// cryptoChunk is one CRYPTO frame's contribution to the reassembled TLS
+// byte stream.
+type cryptoChunk struct {
+	offset uint64
+	data   []byte
+}
+
+// reassembleCrypto walks every frame in a decrypted Initial packet's
+// payload, collects its CRYPTO frames, and concatenates them in offset
+// order into the TLS handshake byte stream they carry. A real
+// ClientHello always starts at offset 0 and normally arrives as one
+// frame. Frames that arrive out of order are sorted by offset, but
+// overlapping CRYPTO frames, or ones that leave a gap, are rejected rather
+// than merged, since an Initial-packet ClientHello that needs real
+// reassembly logic is itself unusual enough to be worth treating as
+// suspicious.
+func reassembleCrypto(payload []byte) ([]byte, error) {
+	r := &reader{buf: payload}
+	var chunks []cryptoChunk
+
+	for r.pos < len(payload) {
+		frameType, _, err := r.readVarint()
+		if err != nil {
+			return nil, fmt.Errorf("truncated frame type: %w", err)
+		}
+
+		switch frameType {
+		case frameTypePadding, frameTypePing:
+			// No fields.
+		case frameTypeAck, frameTypeAckECN:
+			if err := skipAckFrame(r, frameType == frameTypeAckECN); err != nil {
+				return nil, fmt.Errorf("malformed ACK frame: %w", err)
+			}
+		case frameTypeCrypto:
+			offset, _, err := r.readVarint()
+			if err != nil {
+				return nil, fmt.Errorf("truncated CRYPTO offset: %w", err)
+			}
+			length, _, err := r.readVarint()
+			if err != nil {
+				return nil, fmt.Errorf("truncated CRYPTO length: %w", err)
+			}
+			data, err := r.readN(int(length))
+			if err != nil {
+				return nil, fmt.Errorf("truncated CRYPTO data: %w", err)
+			}
+			chunks = append(chunks, cryptoChunk{offset: offset, data: data})
+		case frameTypeConnectionClose, frameTypeConnectionCloseApp:
+			if err := skipConnectionCloseFrame(r, frameType == frameTypeConnectionClose); err != nil {
+				return nil, fmt.Errorf("malformed CONNECTION_CLOSE frame: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected frame type %#x in Initial packet", frameType)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no CRYPTO frame in Initial packet")
+	}
+
+	return concatInOrder(chunks)
+}
+
+// concatInOrder sorts chunks by offset and concatenates them, requiring
+// the result to be contiguous from offset 0.
+func concatInOrder(chunks []cryptoChunk) ([]byte, error) {
+	for i := 1; i < len(chunks); i++ {
+		for j := i; j > 0 && chunks[j-1].offset > chunks[j].offset; j-- {
+			chunks[j-1], chunks[j] = chunks[j], chunks[j-1]
+		}
+	}
+
+	var out []byte
+	var next uint64
+	for _, c := range chunks {
+		if c.offset != next {
+			return nil, fmt.Errorf("non-contiguous CRYPTO data at offset %d, expected %d", c.offset, next)
+		}
+		out = append(out, c.data...)
+		next += uint64(len(c.data))
+	}
+	return out, nil
+}
+
+// skipAckFrame advances r past an ACK frame's fields (RFC 9000 §19.3);
+// its type byte has already been consumed.
+func skipAckFrame(r *reader, ecn bool) error {
+	if _, _, err := r.readVarint(); err != nil { // Largest Acknowledged
+		return err
+	}
+	if _, _, err := r.readVarint(); err != nil { // ACK Delay
+		return err
+	}
+	rangeCount, _, err := r.readVarint()
+	if err != nil {
+		return err
+	}
+	if _, _, err := r.readVarint(); err != nil { // First ACK Range
+		return err
+	}
+	for i := uint64(0); i < rangeCount; i++ {
+		if _, _, err := r.readVarint(); err != nil { // Gap
+			return err
+		}
+		if _, _, err := r.readVarint(); err != nil { // ACK Range Length
+			return err
+		}
+	}
+	if ecn {
+		for i := 0; i < 3; i++ {
+			if _, _, err := r.readVarint(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipConnectionCloseFrame advances r past a CONNECTION_CLOSE frame's
+// fields (RFC 9000 §19.19); its type byte has already been consumed.
+func skipConnectionCloseFrame(r *reader, quicLayer bool) error {
+	if _, _, err := r.readVarint(); err != nil { // Error Code
+		return err
+	}
+	if quicLayer {
+		if _, _, err := r.readVarint(); err != nil { // Frame Type
+			return err
+		}
+	}
+	reasonLen, _, err := r.readVarint()
+	if err != nil {
+		return err
+	}
+	if _, err := r.readN(int(reasonLen)); err != nil {
+		return err
+	}
+	return nil
+}
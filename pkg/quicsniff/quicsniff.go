@@ -0,0 +1,186 @@
+// Package quicsniff parses the QUIC long-header Initial packet of an
+// incoming UDP payload far enough to recover the TLS ClientHello it
+// carries, the same technique v2fly's QUIC sniffer uses to classify
+// traffic before a real QUIC stack ever sees it. Initial packets are
+// only protected by a key derived from public values (the destination
+// connection ID and a per-version public salt), so this requires no
+// server secret - any observer who can see the UDP payload can do this,
+// which is exactly why it is useful for classifying traffic before a
+// handshake completes: a payload that doesn't decrypt and parse as a
+// well-formed Initial packet isn't a real QUIC client.
+package quicsniff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ClientHelloInfo is what Parse recovers from an Initial packet's
+// ClientHello.
+type ClientHelloInfo struct {
+	// Version is the QUIC version from the long header (e.g. 1 for
+	// QUIC v1, 0x6b3343cf for QUIC v2).
+	Version uint32
+	// SNI is the server_name extension's host_name, or "" if absent.
+	SNI string
+	// ALPN lists the application_layer_protocol_negotiation extension's
+	// protocol names, in the order the client sent them.
+	ALPN []string
+}
+
+// ErrNotInitial means pkt is not a long-header Initial packet at all -
+// most likely a short-header (1-RTT) packet, which this package has no
+// public key material to inspect.
+var ErrNotInitial = errors.New("quicsniff: not a QUIC Initial packet")
+
+const (
+	versionQUICv1 = 0x00000001
+	versionQUICv2 = 0x6b3343cf
+
+	longHeaderFormBit = 0x80
+	longHeaderFixedBit = 0x40
+	initialPacketType = 0x00 // bits 5-4 of the first byte for v1; see packetTypeBits
+)
+
+// packetTypeBits extracts the 2-bit long-header packet type, which is
+// encoded differently between QUIC v1 and v2 (RFC 9369 §3.2), and
+// returns it in v1 numbering.
+func packetTypeBits(firstByte byte, version uint32) byte {
+	bits := (firstByte & 0x30) >> 4
+	if version == versionQUICv2 {
+		// v2: 0=Retry, 1=Initial, 2=RTT0, 3=Handshake (v1: 0=Initial,
+		// 1=RTT0, 2=Handshake, 3=Retry).
+		return (bits + 3) & 0x03
+	}
+	return bits
+}
+
+// Parse parses pkt as a single QUIC long-header Initial packet (not a
+// UDP datagram containing several coalesced packets) and returns the
+// ClientHello info it carries. It returns ErrNotInitial if pkt isn't a
+// long-header Initial packet, and a non-nil error for anything that
+// looks like one but fails to decrypt or parse - the latter is the
+// signal a caller should use to tell a real QUIC client from scan noise.
+func Parse(pkt []byte) (*ClientHelloInfo, error) {
+	if len(pkt) < 7 || pkt[0]&longHeaderFormBit == 0 {
+		return nil, ErrNotInitial
+	}
+
+	version := binary.BigEndian.Uint32(pkt[1:5])
+	if packetTypeBits(pkt[0], version) != initialPacketType {
+		return nil, ErrNotInitial
+	}
+	if version != versionQUICv1 && version != versionQUICv2 {
+		return nil, fmt.Errorf("quicsniff: unsupported QUIC version %#x", version)
+	}
+
+	r := &reader{buf: pkt, pos: 5}
+
+	dcidLen, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated destination connection ID length: %w", err)
+	}
+	dcid, err := r.readN(int(dcidLen))
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated destination connection ID: %w", err)
+	}
+
+	scidLen, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated source connection ID length: %w", err)
+	}
+	if _, err := r.readN(int(scidLen)); err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated source connection ID: %w", err)
+	}
+
+	tokenLen, _, err := r.readVarint()
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated token length: %w", err)
+	}
+	if _, err := r.readN(int(tokenLen)); err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated token: %w", err)
+	}
+
+	length, _, err := r.readVarint()
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: truncated length: %w", err)
+	}
+	headerLen := r.pos
+	if int(length) > len(pkt)-headerLen {
+		return nil, fmt.Errorf("quicsniff: declared length %d exceeds packet", length)
+	}
+	// The Initial packet this payload belongs to may be coalesced with
+	// further (Handshake/0-RTT) packets in the same datagram; only the
+	// bytes this packet's own Length field claims belong to it.
+	packet := pkt[:headerLen+int(length)]
+
+	plaintext, err := decryptInitial(packet, headerLen, dcid, version)
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: %w", err)
+	}
+
+	crypto, err := reassembleCrypto(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: %w", err)
+	}
+
+	info, err := parseClientHello(crypto)
+	if err != nil {
+		return nil, fmt.Errorf("quicsniff: %w", err)
+	}
+	info.Version = version
+	return info, nil
+}
+
+// reader is a cursor over a byte slice shared by the header, frame and
+// ClientHello parsers in this package.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) readByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errors.New("short buffer")
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) readN(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.buf) {
+		return nil, errors.New("short buffer")
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) readUint16() (uint16, error) {
+	b, err := r.readN(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// readVarint reads a QUIC variable-length integer (RFC 9000 §16) and
+// returns its value and encoded length in bytes.
+func (r *reader) readVarint() (uint64, int, error) {
+	first, err := r.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	length := 1 << (first >> 6)
+	v := uint64(first & 0x3f)
+	for i := 1; i < length; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, length, nil
+}
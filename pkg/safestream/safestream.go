@@ -0,0 +1,56 @@
+// Package safestream wraps a quic.Stream so closing it coordinates both
+// directions of a bidirectional shutdown, instead of only closing for
+// writing and abandoning whatever the peer still has in flight.
+package safestream
+
+import (
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// DefaultDrainTimeout bounds how long Close waits for the peer to finish
+// writing once the local write side has sent FIN, before giving up and
+// cancelling the read side.
+const DefaultDrainTimeout = 5 * time.Second
+
+// Closer wraps a quic.Stream (analogous to cloudflared's stream closer)
+// so that Close performs an orderly half-close instead of abandoning the
+// read side outright: every place in this codebase that used to call
+// stream.Close() directly after writing now goes through this type, so a
+// peer still mid-write doesn't get hit with a STOP_SENDING the moment the
+// local side is done sending.
+type Closer struct {
+	quic.Stream
+
+	// DrainTimeout bounds how long Close spends reading out whatever the
+	// peer still has in flight after FIN. Zero means DefaultDrainTimeout.
+	DrainTimeout time.Duration
+}
+
+// Wrap returns a Closer around stream using DefaultDrainTimeout.
+func Wrap(stream quic.Stream) *Closer {
+	return &Closer{Stream: stream}
+}
+
+// Close sends FIN on the write side, then drains and discards anything
+// the peer is still sending for up to DrainTimeout before cancelling the
+// read side, so both directions end cleanly: the peer observes its writes
+// being read to completion (or the deadline) rather than an abrupt
+// STOP_SENDING, and any data already in flight toward us is consumed
+// instead of silently dropped.
+func (c *Closer) Close() error {
+	closeErr := c.Stream.Close()
+
+	timeout := c.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	if err := c.Stream.SetReadDeadline(time.Now().Add(timeout)); err == nil {
+		_, _ = io.Copy(io.Discard, c.Stream)
+	}
+	c.Stream.CancelRead(0)
+
+	return closeErr
+}
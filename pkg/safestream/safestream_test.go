@@ -0,0 +1,177 @@
+package safestream
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// loopbackPair dials a QUIC connection to an in-process listener on
+// 127.0.0.1 and returns both ends, so a test can drive a real
+// quic.Stream rather than a fake.
+func loopbackPair(t *testing.T) (server, client quic.Connection) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "safestream-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"safestream-test"},
+	}
+
+	listener, err := quic.ListenAddr("127.0.0.1:0", tlsConf, nil)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	serverCh := make(chan quic.Connection, 1)
+	go func() {
+		conn, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		serverCh <- conn
+	}()
+
+	clientConn, err := quic.DialAddr(context.Background(), listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"safestream-test"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { clientConn.CloseWithError(0, "test done") })
+
+	select {
+	case conn := <-serverCh:
+		t.Cleanup(func() { conn.CloseWithError(0, "test done") })
+		return conn, clientConn
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for server to accept connection")
+		return nil, nil
+	}
+}
+
+// TestClose_WriteSideFinishesFirst verifies that when the write side
+// sends its data and closes before the peer has read it, the peer still
+// observes every byte followed by a clean io.EOF - not a spurious
+// STOP_SENDING-induced read error - and the writer's Close itself
+// returns no error.
+func TestClose_WriteSideFinishesFirst(t *testing.T) {
+	server, client := loopbackPair(t)
+
+	clientStream, err := client.OpenStreamSync(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+
+	payload := []byte("hello from the write side")
+	if _, err := clientStream.Write(payload); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- Wrap(clientStream).Close() }()
+
+	serverStream, err := server.AcceptStream(context.Background())
+	if err != nil {
+		t.Fatalf("failed to accept stream: %v", err)
+	}
+
+	got, err := io.ReadAll(serverStream)
+	if err != nil {
+		t.Fatalf("reader observed spurious error instead of clean EOF: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("got %q, want %q", got, payload)
+	}
+
+	select {
+	case err := <-closeErr:
+		if err != nil {
+			t.Fatalf("Close returned an error: %v", err)
+		}
+	case <-time.After(DefaultDrainTimeout + time.Second):
+		t.Fatal("timed out waiting for Close to return")
+	}
+}
+
+// TestClose_ServerWriteSideFinishesFirst mirrors
+// TestClose_WriteSideFinishesFirst from the other endpoint, confirming
+// the ordered close is symmetric: whichever side finishes writing
+// first, the peer sees every byte followed by a clean io.EOF rather
+// than a spurious error, and the closer's own Close returns no error.
+func TestClose_ServerWriteSideFinishesFirst(t *testing.T) {
+	server, client := loopbackPair(t)
+
+	clientStream, err := client.OpenStreamSync(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	// The server only learns about the stream once the client has sent
+	// something on it.
+	if _, err := clientStream.Write([]byte("ping")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	serverStream, err := server.AcceptStream(context.Background())
+	if err != nil {
+		t.Fatalf("failed to accept stream: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(serverStream, buf); err != nil {
+		t.Fatalf("failed to read ping: %v", err)
+	}
+
+	reply := []byte("pong")
+	if _, err := serverStream.Write(reply); err != nil {
+		t.Fatalf("failed to write reply: %v", err)
+	}
+
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- Wrap(serverStream).Close() }()
+
+	got, err := io.ReadAll(clientStream)
+	if err != nil {
+		t.Fatalf("client observed spurious error instead of clean EOF: %v", err)
+	}
+	if !bytes.Equal(got, reply) {
+		t.Fatalf("got %q, want %q", got, reply)
+	}
+
+	select {
+	case err := <-closeErr:
+		if err != nil {
+			t.Fatalf("server Close returned an error: %v", err)
+		}
+	case <-time.After(DefaultDrainTimeout + time.Second):
+		t.Fatal("timed out waiting for Close to return")
+	}
+}
@@ -0,0 +1,71 @@
+// Package stealth builds TLS fingerprints that mimic popular browsers so
+// the QUIC handshake is harder for DPI middleboxes to distinguish from
+// ordinary web traffic.
+package stealth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+)
+
+// Profile names a browser ClientHello fingerprint to mimic.
+type Profile string
+
+const (
+	ProfileChrome120  Profile = "chrome_120"
+	ProfileFirefox115 Profile = "firefox_115"
+	ProfileSafari16   Profile = "safari_16"
+	ProfileEdge106    Profile = "edge_106"
+	ProfileChrome124  Profile = "chrome_124"
+	ProfileFirefox125 Profile = "firefox_125"
+	ProfileSafari17   Profile = "safari_17"
+	ProfileRandomized Profile = "randomized"
+)
+
+// concreteProfiles lists every profile ProfileRandomized may resolve to.
+var concreteProfiles = []Profile{
+	ProfileChrome120, ProfileFirefox115, ProfileSafari16, ProfileEdge106,
+	ProfileChrome124, ProfileFirefox125, ProfileSafari17,
+}
+
+// spec is the subset of a browser's TLS fingerprint that Go's standard
+// crypto/tls can reproduce: cipher suite and curve preference order, and
+// the ALPN list. Byte-identical extension ordering and GREASE placement
+// require replacing the TLS stack entirely with uTLS - see utls.go, which
+// derives this same spec shape from utls's own ClientHelloID specs so the
+// two stay consistent.
+type spec struct {
+	cipherSuites []uint16
+	curves       []tls.CurveID
+	alpn         []string
+}
+
+// resolve returns the spec for profile, picking a random concrete profile
+// when profile is ProfileRandomized. Profiles uTLS ships a pinned
+// ClientHelloID for are sourced from that ID's own spec (see utls.go);
+// newer profiles without one yet are sourced from the hand-built specs
+// in custom.go. Either way TLSConfig's crypto/tls fallback ends up
+// matching the cipher/curve/ALPN order DialUTLS actually sends on the
+// wire.
+func resolve(profile Profile) (spec, error) {
+	if profile == ProfileRandomized {
+		profile = concreteProfiles[rand.Intn(len(concreteProfiles))]
+	}
+	if chs, ok := customSpecs[profile]; ok {
+		return specFromClientHelloSpec(chs)
+	}
+	id, ok := helloIDs[profile]
+	if !ok {
+		return spec{}, fmt.Errorf("unknown stealth profile %q", profile)
+	}
+	return specFromUTLS(id)
+}
+
+// pickSNI returns a random entry from pool, or "" if pool is empty.
+func pickSNI(pool []string) string {
+	if len(pool) == 0 {
+		return ""
+	}
+	return pool[rand.Intn(len(pool))]
+}
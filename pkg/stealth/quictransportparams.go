@@ -0,0 +1,165 @@
+package stealth
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// TransportParameter is a single QUIC transport parameter, identified by
+// its varint id, in the order it should appear on the wire.
+type TransportParameter struct {
+	ID    uint64
+	Value []byte
+}
+
+// greaseTransportParamID returns the reserved-for-GREASE transport
+// parameter id, per RFC 9000 §18.1: 31*N+27 for some non-negative N. N is
+// fixed per profile below so repeated calls for the same profile are
+// byte-identical, matching how a real browser's GREASE id stays fixed
+// for the lifetime of one build.
+func greaseTransportParamID(n uint64) uint64 {
+	return 31*n + 27
+}
+
+// quicTransportParamOrders pins, per profile, the order a real browser's
+// QUIC client emits its transport parameters, including the GREASE
+// parameter's position. The values themselves are placeholders - only
+// the id order and GREASE placement are fingerprint-relevant - and are
+// filled in by QUICTransportParameters from the caller-supplied local
+// transport parameters where available, or left empty otherwise.
+var quicTransportParamOrders = map[Profile][]uint64{
+	ProfileChrome120: chromeTransportParamOrder,
+	ProfileChrome124: chromeTransportParamOrder,
+	ProfileEdge106:   chromeTransportParamOrder,
+
+	ProfileFirefox115: firefoxTransportParamOrder,
+	ProfileFirefox125: firefoxTransportParamOrder,
+}
+
+// Transport parameter ids, per RFC 9000 section 18.2 and the QUIC
+// version_information draft.
+const (
+	tpMaxIdleTimeout              = 0x01
+	tpMaxUDPPayloadSize           = 0x03
+	tpInitialMaxData              = 0x04
+	tpInitialMaxStreamDataBidiLoc = 0x05
+	tpInitialMaxStreamDataBidiRem = 0x06
+	tpInitialMaxStreamDataUni     = 0x07
+	tpInitialMaxStreamsBidi       = 0x08
+	tpInitialMaxStreamsUni        = 0x09
+	tpAckDelayExponent            = 0x0a
+	tpMaxAckDelay                 = 0x0b
+	tpDisableActiveMigration      = 0x0c
+	tpActiveConnectionIDLimit     = 0x0e
+	tpInitialSourceConnectionID   = 0x0f
+	tpMaxDatagramFrameSize        = 0x20
+	tpVersionInformation          = 0x11 // draft-ietf-quic-version-negotiation
+	tpGREASEQUICBit               = 0x2ab2
+)
+
+// chromeTransportParamOrder is the order Chromium's QUIC stack emits
+// transport parameters, with its GREASE parameter (N=0) placed right
+// after version_information, as observed on the wire.
+var chromeTransportParamOrder = []uint64{
+	tpInitialMaxStreamDataBidiLoc,
+	tpInitialMaxStreamDataBidiRem,
+	tpInitialMaxStreamDataUni,
+	tpInitialMaxData,
+	tpInitialMaxStreamsBidi,
+	tpInitialMaxStreamsUni,
+	tpMaxIdleTimeout,
+	tpMaxUDPPayloadSize,
+	tpDisableActiveMigration,
+	tpVersionInformation,
+	greaseTransportParamID(0),
+	tpActiveConnectionIDLimit,
+	tpInitialSourceConnectionID,
+	tpMaxAckDelay,
+	tpMaxDatagramFrameSize,
+	tpGREASEQUICBit,
+}
+
+// firefoxTransportParamOrder is the order Firefox's neqo QUIC stack
+// emits transport parameters; Firefox does not GREASE its transport
+// parameters.
+var firefoxTransportParamOrder = []uint64{
+	tpInitialMaxStreamDataBidiLoc,
+	tpInitialMaxStreamDataBidiRem,
+	tpInitialMaxStreamDataUni,
+	tpInitialMaxData,
+	tpInitialMaxStreamsBidi,
+	tpInitialMaxStreamsUni,
+	tpAckDelayExponent,
+	tpMaxAckDelay,
+	tpMaxIdleTimeout,
+	tpActiveConnectionIDLimit,
+	tpInitialSourceConnectionID,
+	tpMaxUDPPayloadSize,
+	tpDisableActiveMigration,
+	tpMaxDatagramFrameSize,
+}
+
+// QUICTransportParameters returns profile's transport parameters in the
+// exact order its real QUIC client emits them, including a GREASE
+// parameter at the position that client places one. values supplies the
+// local transport parameter values this connection actually negotiated,
+// keyed by id; any id in the profile's order missing from values is
+// emitted with an empty value placeholder.
+//
+// quic-go builds and encodes its own transport parameter extension
+// internally and does not expose a hook to reorder it or splice in a
+// GREASE entry, so this - like BuildClientHello's relationship to
+// DialWithFakeTLS - cannot yet be wired into the QUIC connections this
+// package actually dials. It exists so the ordering this package claims
+// to reproduce is independently verifiable, and for a future transport
+// that assembles its own QUIC Initial packet.
+func QUICTransportParameters(profile Profile, values map[uint64][]byte) ([]TransportParameter, error) {
+	order, ok := quicTransportParamOrders[profile]
+	if !ok {
+		return nil, fmt.Errorf("stealth: no QUIC transport parameter order for profile %q", profile)
+	}
+
+	params := make([]TransportParameter, 0, len(order))
+	for _, id := range order {
+		params = append(params, TransportParameter{ID: id, Value: values[id]})
+	}
+	return params, nil
+}
+
+// EncodeQUICTransportParameters serializes params back-to-back as
+// id/length/value triples, each field a QUIC variable-length integer,
+// matching the wire format of the QUIC transport_parameters TLS
+// extension (RFC 9000 section 18).
+func EncodeQUICTransportParameters(params []TransportParameter) []byte {
+	var buf []byte
+	for _, p := range params {
+		buf = appendVarint(buf, p.ID)
+		buf = appendVarint(buf, uint64(len(p.Value)))
+		buf = append(buf, p.Value...)
+	}
+	return buf
+}
+
+// appendVarint appends v to buf as a QUIC variable-length integer (RFC
+// 9000 section 16).
+func appendVarint(buf []byte, v uint64) []byte {
+	switch {
+	case v <= 63:
+		return append(buf, byte(v))
+	case v <= 16383:
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(v))
+		b[0] |= 0x40
+		return append(buf, b[:]...)
+	case v <= 1073741823:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(v))
+		b[0] |= 0x80
+		return append(buf, b[:]...)
+	default:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		b[0] |= 0xc0
+		return append(buf, b[:]...)
+	}
+}
@@ -0,0 +1,24 @@
+package stealth
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLSConfig builds a *tls.Config that mimics the given browser profile's
+// cipher suite order, curve preferences and ALPN list, rotating the SNI
+// on every call from sniPool.
+func TLSConfig(profile Profile, sniPool []string) (*tls.Config, error) {
+	s, err := resolve(profile)
+	if err != nil {
+		return nil, fmt.Errorf("stealth: %w", err)
+	}
+
+	return &tls.Config{
+		ServerName:       pickSNI(sniPool),
+		MinVersion:       tls.VersionTLS13,
+		CipherSuites:     s.cipherSuites,
+		CurvePreferences: s.curves,
+		NextProtos:       s.alpn,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package stealth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// helloIDs maps each Profile that uTLS ships a pinned ClientHelloID for to
+// that ID, which reproduces the browser's real extension order, GREASE
+// placement and supported-groups/signature-algorithm lists. The newer
+// profiles are in customSpecs instead, and ProfileFirefox115 is served by
+// uTLS's Firefox 105 ClientHelloID.
+var helloIDs = map[Profile]utls.ClientHelloID{
+	ProfileChrome120:  utls.HelloChrome_120,
+	ProfileFirefox115: utls.HelloFirefox_105,
+	ProfileSafari16:   utls.HelloSafari_16_0,
+	ProfileEdge106:    utls.HelloEdge_106,
+}
+
+// specFromUTLS derives a crypto/tls-compatible spec (cipher suite order,
+// curve preferences, ALPN) from uTLS's own ClientHelloSpec for id, so
+// BuildClientHello's wire bytes and TLSConfig's crypto/tls fallback agree
+// on fingerprint.
+func specFromUTLS(id utls.ClientHelloID) (spec, error) {
+	chs, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		return spec{}, fmt.Errorf("stealth: no uTLS spec for %s: %w", id.Str(), err)
+	}
+	return specFromClientHelloSpec(chs)
+}
+
+// specFromClientHelloSpec derives a crypto/tls-compatible spec (cipher
+// suite order, curve preferences, ALPN) from a uTLS ClientHelloSpec,
+// whether that spec came from a pinned uTLS ClientHelloID or was
+// hand-built in custom.go.
+func specFromClientHelloSpec(chs utls.ClientHelloSpec) (spec, error) {
+	s := spec{cipherSuites: chs.CipherSuites}
+	for _, ext := range chs.Extensions {
+		switch e := ext.(type) {
+		case *utls.SupportedCurvesExtension:
+			for _, c := range e.Curves {
+				s.curves = append(s.curves, tls.CurveID(c))
+			}
+		case *utls.ALPNExtension:
+			s.alpn = append(s.alpn, e.AlpnProtocols...)
+		}
+	}
+	return s, nil
+}
+
+// BuildClientHello runs a real uTLS handshake far enough to marshal the
+// ClientHello record that profile would send to serverName, byte-for-byte
+// extension order, GREASE values and all. It returns the raw bytes
+// without completing (or even transmitting) the handshake.
+//
+// quic-go's QUIC-TLS layer does not allow swapping in a foreign TLS
+// stack, so DialWithFakeTLS (in the client binary) still drives the
+// actual handshake through crypto/tls configured via TLSConfig, which
+// reproduces profile's cipher/curve/ALPN order but not its exact
+// extension bytes. BuildClientHello exists for transports that do their
+// own record layer and can embed these exact bytes (e.g. a future
+// fronted-WebSocket carrier), and so the fingerprint this package claims
+// to produce is independently verifiable.
+func BuildClientHello(profile Profile, serverName string) ([]byte, error) {
+	if profile == ProfileRandomized {
+		profile = concreteProfiles[0]
+	}
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	if chs, ok := customSpecs[profile]; ok {
+		uconn := utls.UClient(client, &utls.Config{ServerName: serverName}, utls.HelloCustom)
+		defer uconn.Close()
+		if err := uconn.ApplyPreset(&chs); err != nil {
+			return nil, fmt.Errorf("stealth: failed to apply %s preset: %w", profile, err)
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			return nil, fmt.Errorf("stealth: failed to build %s ClientHello: %w", profile, err)
+		}
+		return uconn.HandshakeState.Hello.Raw, nil
+	}
+
+	id, ok := helloIDs[profile]
+	if !ok {
+		return nil, fmt.Errorf("stealth: unknown stealth profile %q", profile)
+	}
+
+	uconn := utls.UClient(client, &utls.Config{ServerName: serverName}, id)
+	defer uconn.Close()
+
+	if err := uconn.BuildHandshakeState(); err != nil {
+		return nil, fmt.Errorf("stealth: failed to build %s ClientHello: %w", id.Str(), err)
+	}
+	return uconn.HandshakeState.Hello.Raw, nil
+}
@@ -0,0 +1,345 @@
+package stealth
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// handshakeTypeClientHello is the TLS Handshake message type a
+// ClientHello is always marshaled with (RFC 8446 §4).
+const handshakeTypeClientHello = 0x01
+
+// extOrder is the sequence of TLS extension type IDs a ClientHello sent
+// them in, with grease=true marking a slot whose id is a GREASE value
+// (RFC 8701) rather than a fixed extension - GREASE values are chosen at
+// random per handshake, so the position of the slot is what the profile
+// pins, not its literal id.
+type extOrder struct {
+	id     uint16
+	grease bool
+}
+
+// extensionOrder walks a marshaled ClientHello handshake message (the
+// same shape BuildClientHello returns, and that pkg/quicsniff's
+// parseClientHello decrypts off the wire) and returns the extension type
+// IDs in the order they appear, without interpreting their contents.
+func extensionOrder(raw []byte) ([]extOrder, error) {
+	pos := 0
+	readN := func(n int) ([]byte, error) {
+		if pos+n > len(raw) {
+			return nil, fmt.Errorf("truncated ClientHello at offset %d, want %d bytes", pos, n)
+		}
+		b := raw[pos : pos+n]
+		pos += n
+		return b, nil
+	}
+
+	msgType, err := readN(1)
+	if err != nil {
+		return nil, err
+	}
+	if msgType[0] != handshakeTypeClientHello {
+		return nil, fmt.Errorf("expected ClientHello, got handshake type %#x", msgType[0])
+	}
+	msgLen, err := readN(3)
+	if err != nil {
+		return nil, fmt.Errorf("truncated handshake length: %w", err)
+	}
+	bodyLen := int(msgLen[0])<<16 | int(msgLen[1])<<8 | int(msgLen[2])
+	if pos+bodyLen > len(raw) {
+		return nil, fmt.Errorf("handshake length %d exceeds ClientHello", bodyLen)
+	}
+
+	if _, err := readN(2); err != nil { // legacy_version
+		return nil, fmt.Errorf("truncated client_version: %w", err)
+	}
+	if _, err := readN(32); err != nil { // random
+		return nil, fmt.Errorf("truncated random: %w", err)
+	}
+	sessionIDLen, err := readN(1)
+	if err != nil {
+		return nil, fmt.Errorf("truncated session_id length: %w", err)
+	}
+	if _, err := readN(int(sessionIDLen[0])); err != nil {
+		return nil, fmt.Errorf("truncated session_id: %w", err)
+	}
+	cipherSuitesLen, err := readN(2)
+	if err != nil {
+		return nil, fmt.Errorf("truncated cipher_suites length: %w", err)
+	}
+	if _, err := readN(int(binary.BigEndian.Uint16(cipherSuitesLen))); err != nil {
+		return nil, fmt.Errorf("truncated cipher_suites: %w", err)
+	}
+	compressionLen, err := readN(1)
+	if err != nil {
+		return nil, fmt.Errorf("truncated compression_methods length: %w", err)
+	}
+	if _, err := readN(int(compressionLen[0])); err != nil {
+		return nil, fmt.Errorf("truncated compression_methods: %w", err)
+	}
+
+	if pos >= len(raw) {
+		return nil, nil
+	}
+	extensionsLen, err := readN(2)
+	if err != nil {
+		return nil, fmt.Errorf("truncated extensions length: %w", err)
+	}
+	extensionsEnd := pos + int(binary.BigEndian.Uint16(extensionsLen))
+	if extensionsEnd > len(raw) {
+		return nil, fmt.Errorf("extensions length exceeds ClientHello")
+	}
+
+	var order []extOrder
+	for pos < extensionsEnd {
+		extType, err := readN(2)
+		if err != nil {
+			return nil, fmt.Errorf("truncated extension type: %w", err)
+		}
+		extLen, err := readN(2)
+		if err != nil {
+			return nil, fmt.Errorf("truncated extension length: %w", err)
+		}
+		if _, err := readN(int(binary.BigEndian.Uint16(extLen))); err != nil {
+			return nil, fmt.Errorf("truncated extension data: %w", err)
+		}
+		id := binary.BigEndian.Uint16(extType)
+		order = append(order, extOrder{id: id, grease: isGREASEExtension(id)})
+	}
+	return order, nil
+}
+
+// isGREASEExtension reports whether id is one of the 16 reserved GREASE
+// values (RFC 8701 §2: both bytes equal, low nibble 0xA), the same test
+// uTLS itself uses to recognize a GREASE extension type it generated.
+func isGREASEExtension(id uint16) bool {
+	return (id>>8) == id&0xff && id&0x0f == 0x0a
+}
+
+// TLS extension type IDs (RFC 8446 §4.2 plus the ALPS/compress_certificate
+// values uTLS assigns, used below to pin each custom profile's fixed
+// extension order without re-deriving it from customSpecs.
+const (
+	extIDServerName           = 0
+	extIDStatusRequest        = 5
+	extIDSupportedGroups      = 10
+	extIDECPointFormats       = 11
+	extIDSignatureAlgorithms  = 13
+	extIDALPN                 = 16
+	extIDSCT                  = 18
+	extIDExtendedMasterSecret = 23
+	extIDCompressCertificate  = 27
+	extIDPadding              = 21
+	extIDSessionTicket        = 35
+	extIDSupportedVersions    = 43
+	extIDPSKModes             = 45
+	extIDKeyShare             = 51
+	extIDApplicationSettings  = 17513
+	extIDRenegotiationInfo    = 0xff01
+)
+
+func grease() extOrder         { return extOrder{grease: true} }
+func fixed(id uint16) extOrder { return extOrder{id: id} }
+
+// trimTrailingPadding drops a trailing padding extension from got: uTLS
+// sizes padding to the ClientHello's total length (BoringPaddingStyle),
+// which depends on the SNI this test picked, so whether the extension is
+// emitted at all isn't part of what a profile pins - only the fixed
+// extensions before it are.
+func trimTrailingPadding(got []extOrder) []extOrder {
+	if n := len(got); n > 0 && !got[n-1].grease && got[n-1].id == extIDPadding {
+		return got[:n-1]
+	}
+	return got
+}
+
+func sameOrder(t *testing.T, got, want []extOrder) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("extension count = %d, want %d\ngot:  %+v\nwant: %+v", len(got), len(want), got, want)
+	}
+	for i := range want {
+		if want[i].grease {
+			if !got[i].grease {
+				t.Fatalf("extension %d: got fixed id %#x, want a GREASE extension", i, got[i].id)
+			}
+			continue
+		}
+		if got[i].grease || got[i].id != want[i].id {
+			t.Fatalf("extension %d: got %#x (grease=%v), want %#x", i, got[i].id, got[i].grease, want[i].id)
+		}
+	}
+}
+
+// TestBuildClientHelloExtensionOrder asserts BuildClientHello's output
+// puts each custom profile's extensions in exactly the order
+// chrome124Spec/firefox125Spec/safari17Spec declare them in custom.go -
+// the order a DPI box fingerprinting ClientHellos actually keys on. These
+// three profiles are hand-built in this package (not sourced from a uTLS
+// HelloXXX constant), so this is the only place that order is pinned
+// against regressions.
+func TestBuildClientHelloExtensionOrder(t *testing.T) {
+	tests := []struct {
+		profile Profile
+		want    []extOrder
+	}{
+		{
+			profile: ProfileChrome124,
+			want: []extOrder{
+				grease(),
+				fixed(extIDServerName),
+				fixed(extIDExtendedMasterSecret),
+				fixed(extIDRenegotiationInfo),
+				fixed(extIDSupportedGroups),
+				fixed(extIDECPointFormats),
+				fixed(extIDSessionTicket),
+				fixed(extIDALPN),
+				fixed(extIDStatusRequest),
+				fixed(extIDSignatureAlgorithms),
+				fixed(extIDSCT),
+				fixed(extIDKeyShare),
+				fixed(extIDPSKModes),
+				fixed(extIDSupportedVersions),
+				fixed(extIDCompressCertificate),
+				fixed(extIDApplicationSettings),
+				grease(),
+			},
+		},
+		{
+			profile: ProfileFirefox125,
+			want: []extOrder{
+				fixed(extIDServerName),
+				fixed(extIDExtendedMasterSecret),
+				fixed(extIDRenegotiationInfo),
+				fixed(extIDSupportedGroups),
+				fixed(extIDECPointFormats),
+				fixed(extIDSessionTicket),
+				fixed(extIDALPN),
+				fixed(extIDStatusRequest),
+				fixed(extIDKeyShare),
+				fixed(extIDSignatureAlgorithms),
+				fixed(extIDPSKModes),
+				fixed(extIDSupportedVersions),
+				// FakeRecordSizeLimitExtension (0x001c / 28).
+				fixed(28),
+			},
+		},
+		{
+			profile: ProfileSafari17,
+			want: []extOrder{
+				fixed(extIDServerName),
+				fixed(extIDExtendedMasterSecret),
+				fixed(extIDRenegotiationInfo),
+				fixed(extIDSupportedGroups),
+				fixed(extIDECPointFormats),
+				fixed(extIDALPN),
+				fixed(extIDStatusRequest),
+				fixed(extIDKeyShare),
+				fixed(extIDSignatureAlgorithms),
+				fixed(extIDSCT),
+				fixed(extIDPSKModes),
+				fixed(extIDSupportedVersions),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.profile), func(t *testing.T) {
+			raw, err := BuildClientHello(tt.profile, "example.com")
+			if err != nil {
+				t.Fatalf("BuildClientHello(%s): %v", tt.profile, err)
+			}
+			got, err := extensionOrder(raw)
+			if err != nil {
+				t.Fatalf("extensionOrder: %v", err)
+			}
+			sameOrder(t, trimTrailingPadding(got), tt.want)
+		})
+	}
+}
+
+// shufflesExtensions lists profiles whose underlying uTLS ClientHelloID
+// runs ShuffleChromeTLSExtensions - real Chrome (106+) randomizes
+// ClientHello extension order per connection to resist ossification, and
+// uTLS reproduces that, so TestBuildClientHelloDeterministicOrder must
+// not expect a fixed order for them.
+var shufflesExtensions = map[Profile]bool{
+	ProfileChrome120: true,
+}
+
+// sameExtensionSet reports whether got and want contain the same
+// extensions with the same multiplicity, ignoring order - the invariant
+// that holds across calls for a profile whose order is shuffled.
+func sameExtensionSet(t *testing.T, got, want []extOrder) {
+	t.Helper()
+	count := func(order []extOrder) (fixed map[uint16]int, greaseN int) {
+		fixed = make(map[uint16]int)
+		for _, e := range order {
+			if e.grease {
+				greaseN++
+				continue
+			}
+			fixed[e.id]++
+		}
+		return
+	}
+	gotFixed, gotGrease := count(got)
+	wantFixed, wantGrease := count(want)
+	if gotGrease != wantGrease || len(gotFixed) != len(wantFixed) {
+		t.Fatalf("extension set mismatch: got %+v (grease=%d), want %+v (grease=%d)", gotFixed, gotGrease, wantFixed, wantGrease)
+	}
+	for id, n := range wantFixed {
+		if gotFixed[id] != n {
+			t.Fatalf("extension %#x: got %d occurrences, want %d", id, gotFixed[id], n)
+		}
+	}
+}
+
+// TestBuildClientHelloDeterministicOrder covers the profiles sourced from
+// uTLS's own pinned HelloXXX ClientHelloIDs (see helloIDs in utls.go)
+// rather than the hand-built specs in custom.go. This package doesn't
+// re-derive uTLS's internal extension layout for those, so it can't
+// assert a literal expected order without just copying uTLS's source -
+// instead it asserts the property callers actually rely on: the same
+// profile produces the same extensions on every call (in the same order,
+// unless the profile is one Chrome itself shuffles - see
+// shufflesExtensions), and a server_name (SNI) extension is present.
+func TestBuildClientHelloDeterministicOrder(t *testing.T) {
+	for profile := range helloIDs {
+		t.Run(string(profile), func(t *testing.T) {
+			first, err := BuildClientHello(profile, "example.com")
+			if err != nil {
+				t.Fatalf("BuildClientHello(%s): %v", profile, err)
+			}
+			second, err := BuildClientHello(profile, "example.com")
+			if err != nil {
+				t.Fatalf("BuildClientHello(%s) second call: %v", profile, err)
+			}
+
+			firstOrder, err := extensionOrder(first)
+			if err != nil {
+				t.Fatalf("extensionOrder(first): %v", err)
+			}
+			secondOrder, err := extensionOrder(second)
+			if err != nil {
+				t.Fatalf("extensionOrder(second): %v", err)
+			}
+			if shufflesExtensions[profile] {
+				sameExtensionSet(t, secondOrder, firstOrder)
+			} else {
+				sameOrder(t, secondOrder, firstOrder)
+			}
+
+			sawSNI := false
+			for _, e := range firstOrder {
+				if !e.grease && e.id == extIDServerName {
+					sawSNI = true
+				}
+			}
+			if !sawSNI {
+				t.Fatalf("%s: ClientHello carries no server_name extension", profile)
+			}
+		})
+	}
+}
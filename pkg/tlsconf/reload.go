@@ -0,0 +1,81 @@
+package tlsconf
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// reload re-reads the certificate and CA pool from disk (or regenerates a
+// self-signed certificate) and swaps them into the live config atomically.
+func (m *Manager) reload() error {
+	cert, err := m.readCertificate()
+	if err != nil {
+		return err
+	}
+	pool, err := m.readCAPool()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cert = cert
+	m.caPool = pool
+	m.mu.Unlock()
+	return nil
+}
+
+// Reload forces an immediate re-read of the certificate and CA bundle.
+// Connections already established are unaffected; new connections pick up
+// the rotated material right away.
+func (m *Manager) Reload() error {
+	return m.reload()
+}
+
+// WatchReload starts a background loop that calls Reload whenever the CA
+// bundle's mtime changes or sighup delivers a signal, until stop is
+// closed. It is safe to pass a nil sighup channel when the caller does not
+// want signal-triggered reloads (e.g. on platforms without SIGHUP).
+func (m *Manager) WatchReload(stop <-chan struct{}, sighup <-chan os.Signal, pollInterval time.Duration) {
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastMod := m.caModTime()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sighup:
+			if err := m.Reload(); err != nil {
+				log.Printf("tlsconf: reload on SIGHUP failed: %v", err)
+			} else {
+				log.Printf("tlsconf: CA pool rotated via SIGHUP")
+			}
+		case <-ticker.C:
+			mod := m.caModTime()
+			if mod.IsZero() || mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			if err := m.Reload(); err != nil {
+				log.Printf("tlsconf: reload on CA change failed: %v", err)
+			} else {
+				log.Printf("tlsconf: CA pool rotated after file change")
+			}
+		}
+	}
+}
+
+func (m *Manager) caModTime() time.Time {
+	if m.cfg.CA == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(m.cfg.CA)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,273 @@
+package tlsconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// caIdentity is a throwaway self-signed CA plus one leaf certificate it
+// issued, used to build distinct trust roots for
+// TestReload_RotatesCAWithoutDroppingExistingConnections.
+type caIdentity struct {
+	caPEM []byte
+	leaf  tls.Certificate
+}
+
+// newCAIdentity generates a fresh ECDSA CA and a leaf certificate it
+// signs, valid as both a server and client identity so the same helper
+// builds every identity this test needs.
+func newCAIdentity(t *testing.T, commonName string) caIdentity {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName + "-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate leaf key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf certificate: %v", err)
+	}
+
+	return caIdentity{
+		caPEM: encodePEM(caDER, "CERTIFICATE"),
+		leaf: tls.Certificate{
+			Certificate: [][]byte{leafDER},
+			PrivateKey:  leafKey,
+		},
+	}
+}
+
+func encodePEM(der []byte, blockType string) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+// clientDial opens a mutual-TLS connection to addr presenting clientCert,
+// trusting only serverCAPool to verify the server's certificate.
+func clientDial(t *testing.T, addr string, clientCert tls.Certificate, serverCAPool *x509.CertPool) *tls.Conn {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      serverCAPool,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", addr, err)
+	}
+	return conn
+}
+
+func echoOnce(t *testing.T, a, b net.Conn, payload string) {
+	t.Helper()
+	if _, err := a.Write([]byte(payload)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, len(payload))
+	if _, err := io.ReadFull(b, buf); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(buf) != payload {
+		t.Fatalf("got %q, want %q", buf, payload)
+	}
+}
+
+// TestReload_RotatesCAWithoutDroppingExistingConnections rotates the
+// server's trusted CA bundle mid-flight (old CA -> old+new CA) and
+// verifies a client presenting a certificate signed by the new CA is
+// accepted once Reload runs, while a connection already established under
+// the old CA keeps working untouched - Reload only swaps the pool
+// handshakes consult going forward (see TLSConfig's GetConfigForClient),
+// it never touches an accepted net.Conn.
+func TestReload_RotatesCAWithoutDroppingExistingConnections(t *testing.T) {
+	oldCA := newCAIdentity(t, "old-client")
+	newCA := newCAIdentity(t, "new-client")
+	serverID := newCAIdentity(t, "server")
+
+	caPath := filepath.Join(t.TempDir(), "ca-bundle.pem")
+	if err := os.WriteFile(caPath, oldCA.caPEM, 0o600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+	certPath := filepath.Join(t.TempDir(), "server-cert.pem")
+	keyPath := filepath.Join(t.TempDir(), "server-key.pem")
+	writeKeyPair(t, certPath, keyPath, serverID.leaf)
+
+	manager, err := NewManager(RoleServer, RoleConfig{
+		CA:   caPath,
+		Cert: certPath,
+		Key:  keyPath,
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", manager.TLSConfig(nil))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// listener.Accept returns as soon as the TCP handshake completes; the
+	// TLS handshake itself only runs lazily on a conn's first Read or
+	// Write. Drive it to completion in its own goroutine per connection
+	// so a client blocked in tls.Dial isn't waiting on a server that
+	// never calls Read/Write. Report both outcomes on accepted: a client
+	// whose certificate the server's CA pool doesn't cover can still
+	// complete its own side of a TLS 1.3 handshake (RFC 8446 lets a
+	// client decline by sending an empty Certificate message) even though
+	// the server then rejects it for RequireAndVerifyClientCert - so
+	// whether a given dial was actually accepted has to be read off this
+	// channel, not off the client's dial error.
+	type accepted struct {
+		conn net.Conn
+		err  error
+	}
+	serverConns := make(chan accepted, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil {
+					conn.Close()
+					serverConns <- accepted{err: err}
+					return
+				}
+				serverConns <- accepted{conn: conn}
+			}()
+		}
+	}()
+
+	serverCAPool := x509.NewCertPool()
+	if !serverCAPool.AppendCertsFromPEM(serverID.caPEM) {
+		t.Fatalf("failed to build server trust pool")
+	}
+
+	nextResult := func(t *testing.T) accepted {
+		t.Helper()
+		select {
+		case a := <-serverConns:
+			return a
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the server to finish a handshake")
+			return accepted{}
+		}
+	}
+
+	// Establish a long-lived connection under the original CA before
+	// rotating anything.
+	oldClientConn := clientDial(t, listener.Addr().String(), oldCA.leaf, serverCAPool)
+	defer oldClientConn.Close()
+
+	oldResult := nextResult(t)
+	if oldResult.err != nil {
+		t.Fatalf("expected the old-CA client to be accepted, got: %v", oldResult.err)
+	}
+	oldServerConn := oldResult.conn
+	defer oldServerConn.Close()
+	echoOnce(t, oldClientConn, oldServerConn, "hello before rotation")
+
+	// A client signed by the new CA must be rejected before rotation.
+	rejectedConn, _ := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{newCA.leaf},
+		RootCAs:      serverCAPool,
+	})
+	if rejectedConn != nil {
+		defer rejectedConn.Close()
+	}
+	if rejected := nextResult(t); rejected.err == nil {
+		rejected.conn.Close()
+		t.Fatal("expected new-CA client to be rejected before rotation")
+	}
+
+	// Rotate the CA bundle to trust both the old and the new CA, and
+	// reload.
+	combined := append(append([]byte{}, oldCA.caPEM...), newCA.caPEM...)
+	if err := os.WriteFile(caPath, combined, 0o600); err != nil {
+		t.Fatalf("failed to rewrite CA bundle: %v", err)
+	}
+	if err := manager.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	// The new-CA client is now accepted.
+	newClientConn := clientDial(t, listener.Addr().String(), newCA.leaf, serverCAPool)
+	defer newClientConn.Close()
+
+	newResult := nextResult(t)
+	if newResult.err != nil {
+		t.Fatalf("expected the new-CA client to be accepted after rotation, got: %v", newResult.err)
+	}
+	newServerConn := newResult.conn
+	defer newServerConn.Close()
+	echoOnce(t, newClientConn, newServerConn, "hello after rotation")
+
+	// The connection established under the old CA is unaffected by the
+	// rotation.
+	echoOnce(t, oldClientConn, oldServerConn, "still alive after rotation")
+}
+
+// writeKeyPair PEM-encodes cert and writes both files NewManager expects
+// on disk when auto_certs is not set.
+func writeKeyPair(t *testing.T, certPath, keyPath string, cert tls.Certificate) {
+	t.Helper()
+	if err := os.WriteFile(certPath, encodePEM(cert.Certificate[0], "CERTIFICATE"), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	key, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("unexpected private key type %T", cert.PrivateKey)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	if err := os.WriteFile(keyPath, encodePEM(der, "EC PRIVATE KEY"), 0o600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package tlsconf
+
+import (
+	"crypto/x509"
+	"strings"
+)
+
+// SPIFFEID returns the spiffe:// URI SAN on cert, if any, so callers like
+// handleConnection can route per-tenant without parsing the certificate
+// themselves.
+func SPIFFEID(cert *x509.Certificate) (string, bool) {
+	for _, uri := range cert.URIs {
+		if strings.HasPrefix(uri.Scheme, "spiffe") {
+			return uri.String(), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,172 @@
+// Package tlsconf builds and hot-reloads mutual-auth TLS configurations
+// shared by the QuicFuscate servers (the plain echo server and the
+// StealthQUIC server). The client builds its TLS configuration through
+// the stealth package instead.
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Role distinguishes the three kinds of TLS identity this package can
+// build: a listening server, a dialing client, and a "peer" identity used
+// by services that both listen and dial (e.g. mesh-style connections).
+type Role string
+
+const (
+	RoleServer Role = "server"
+	RoleClient Role = "client"
+	RolePeer   Role = "peer"
+)
+
+// RoleConfig is the YAML shape for a single role's TLS material.
+type RoleConfig struct {
+	CA         string `yaml:"ca"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	AutoCerts  bool   `yaml:"auto_certs"`
+	SkipCA     bool   `yaml:"skip_ca"`
+}
+
+// Manager owns a live *tls.Config for one role and keeps its trusted CA
+// pool (and, for auto-generated identities, its own certificate) up to
+// date as the underlying files change or a reload is requested.
+type Manager struct {
+	role Role
+	cfg  RoleConfig
+
+	mu        sync.RWMutex
+	cert      tls.Certificate
+	caPool    *x509.CertPool
+}
+
+// NewManager loads cfg's certificate and CA pool and returns a Manager
+// ready to serve a live *tls.Config via TLSConfig.
+func NewManager(role Role, cfg RoleConfig) (*Manager, error) {
+	m := &Manager{role: role, cfg: cfg}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TLSConfig returns a *tls.Config wired to always read the Manager's
+// current certificate and CA pool, so connections dialed or accepted
+// after a Reload immediately see the new trust root.
+//
+// It deliberately leaves CipherSuites and CurvePreferences unset: a
+// server built from this config is a relaxed acceptor that takes
+// whatever suite/curve/extension order the client offers (including the
+// GREASE values and browser-mimicking orderings pkg/stealth's clients
+// send), rather than pinning a list a stealth ClientHello might not
+// match.
+func (m *Manager) TLSConfig(alpn []string) *tls.Config {
+	cfg := &tls.Config{
+		NextProtos: alpn,
+		MinVersion: tls.VersionTLS13,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return &m.cert, nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return &m.cert, nil
+		},
+	}
+
+	if !m.cfg.SkipCA {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.VerifyPeerCertificate = m.verifyPeerCertificate
+		// ClientCAs/RootCAs are re-read from the live pool on every
+		// handshake via the callbacks below rather than snapshotted once,
+		// so a rotated root takes effect for new connections immediately.
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			clone := cfg.Clone()
+			clone.ClientCAs = m.caPool
+			clone.RootCAs = m.caPool
+			clone.GetConfigForClient = nil
+			return clone, nil
+		}
+	}
+
+	m.mu.RLock()
+	cfg.RootCAs = m.caPool
+	cfg.ClientCAs = m.caPool
+	m.mu.RUnlock()
+
+	return cfg
+}
+
+// verifyPeerCertificate additionally validates peer certificates against
+// the live CA pool, since GetConfigForClient only applies on the server
+// side of a handshake.
+func (m *Manager) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	m.mu.RLock()
+	pool := m.caPool
+	m.mu.RUnlock()
+	if pool == nil {
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("tlsconf: failed to parse peer certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("tlsconf: no peer certificate presented")
+	}
+
+	opts := x509.VerifyOptions{Roots: pool, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// PeerCertificate returns the verified peer certificate from a completed
+// TLS connection state, used by callers that need the SPIFFE identity
+// (see spiffe.go).
+func PeerCertificate(state tls.ConnectionState) (*x509.Certificate, bool) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, false
+	}
+	return state.PeerCertificates[0], true
+}
+
+func (m *Manager) readCertificate() (tls.Certificate, error) {
+	if m.cfg.AutoCerts {
+		return generateSelfSigned()
+	}
+	if m.cfg.Cert == "" || m.cfg.Key == "" {
+		return tls.Certificate{}, fmt.Errorf("tlsconf: cert/key required unless auto_certs is set")
+	}
+	return tls.LoadX509KeyPair(m.cfg.Cert, m.cfg.Key)
+}
+
+func (m *Manager) readCAPool() (*x509.CertPool, error) {
+	if m.cfg.SkipCA || m.cfg.CA == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(m.cfg.CA)
+	if err != nil {
+		return nil, fmt.Errorf("tlsconf: failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("tlsconf: no certificates found in %s", m.cfg.CA)
+	}
+	return pool, nil
+}
@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// FrontedWSConfig configures the domain-fronted WebSocket obfuscator.
+type FrontedWSConfig struct {
+	// FrontDomain is the TLS SNI/Host presented to the censor-visible CDN
+	// edge, e.g. a popular CDN-fronted domain.
+	FrontDomain string `yaml:"front_domain"`
+	// RealHost is the Host header sent inside the CONNECT request, naming
+	// the actual QuicFuscate endpoint behind the CDN.
+	RealHost string `yaml:"real_host"`
+	// WSPath is the path the WebSocket upgrade is requested against.
+	WSPath string `yaml:"ws_path"`
+}
+
+// NewFrontedWS builds an Obfuscator that tunnels every QUIC datagram over
+// a domain-fronted WebSocket connection: a TLS connection is opened to
+// cfg.FrontDomain (the SNI a censor sees), an HTTP CONNECT naming
+// cfg.RealHost is issued over it, and the resulting pipe is upgraded to a
+// WebSocket that carries one UDP datagram per binary frame.
+func NewFrontedWS(cfg FrontedWSConfig) (Obfuscator, error) {
+	if cfg.FrontDomain == "" || cfg.RealHost == "" {
+		return nil, fmt.Errorf("transport: fronted-ws mode requires front_domain and real_host")
+	}
+	if cfg.WSPath == "" {
+		cfg.WSPath = "/ws"
+	}
+	return &frontedWSObfuscator{cfg: cfg}, nil
+}
+
+type frontedWSObfuscator struct {
+	cfg FrontedWSConfig
+}
+
+func (o *frontedWSObfuscator) WrapPacketConn(local net.PacketConn) net.PacketConn {
+	return &frontedWSPacketConn{cfg: o.cfg, local: local}
+}
+
+// frontedWSPacketConn lazily establishes the fronted WS tunnel on first
+// use and presents it as a net.PacketConn so it can be handed straight to
+// quic.Dial.
+type frontedWSPacketConn struct {
+	net.PacketConn
+	cfg   FrontedWSConfig
+	local net.PacketConn
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *frontedWSPacketConn) ensureConn() (*websocket.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	tlsConn, err := tls.Dial("tcp", c.cfg.FrontDomain+":443", &tls.Config{ServerName: c.cfg.FrontDomain})
+	if err != nil {
+		return nil, fmt.Errorf("transport: fronted TLS dial failed: %w", err)
+	}
+
+	connectReq := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", c.cfg.RealHost, c.cfg.RealHost)
+	if _, err := tlsConn.Write([]byte(connectReq)); err != nil {
+		tlsConn.Close()
+		return nil, fmt.Errorf("transport: CONNECT request failed: %w", err)
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		tlsConn.Close()
+		return nil, fmt.Errorf("transport: failed to read CONNECT response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		tlsConn.Close()
+		return nil, fmt.Errorf("transport: CONNECT rejected with status %s", resp.Status)
+	}
+
+	wsURL := url.URL{Scheme: "wss", Host: c.cfg.RealHost, Path: c.cfg.WSPath}
+	wsConn, _, err := websocket.NewClient(tlsConn, &wsURL, nil, 4096, 4096)
+	if err != nil {
+		tlsConn.Close()
+		return nil, fmt.Errorf("transport: WebSocket upgrade failed: %w", err)
+	}
+
+	c.conn = wsConn
+	return wsConn, nil
+}
+
+func (c *frontedWSPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	conn, err := c.ensureConn()
+	if err != nil {
+		return 0, nil, err
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return 0, nil, fmt.Errorf("transport: fronted WS read failed: %w", err)
+	}
+	n := copy(p, data)
+	return n, frontedWSAddr{host: c.cfg.RealHost}, nil
+}
+
+func (c *frontedWSPacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	conn, err := c.ensureConn()
+	if err != nil {
+		return 0, err
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, fmt.Errorf("transport: fronted WS write failed: %w", err)
+	}
+	return len(p), nil
+}
+
+func (c *frontedWSPacketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *frontedWSPacketConn) LocalAddr() net.Addr {
+	return frontedWSAddr{host: c.cfg.FrontDomain}
+}
+
+func (c *frontedWSPacketConn) SetDeadline(t time.Time) error      { return nil }
+func (c *frontedWSPacketConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *frontedWSPacketConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// frontedWSAddr is a minimal net.Addr implementation for the virtual
+// fronted-WS endpoint.
+type frontedWSAddr struct{ host string }
+
+func (a frontedWSAddr) Network() string { return "fronted-ws" }
+func (a frontedWSAddr) String() string  { return a.host }
@@ -0,0 +1,11 @@
+package transport
+
+import "net"
+
+// NullObfuscator passes packets through unmodified.
+type NullObfuscator struct{}
+
+// WrapPacketConn returns conn unchanged.
+func (NullObfuscator) WrapPacketConn(conn net.PacketConn) net.PacketConn {
+	return conn
+}
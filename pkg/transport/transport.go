@@ -0,0 +1,52 @@
+// Package transport wraps the net.PacketConn handed to quic-go so the
+// wire format can be disguised before QUIC ever sees it, giving
+// QuicFuscate a censorship-resistance story beyond TLS fingerprint
+// mimicry alone.
+package transport
+
+import (
+	"fmt"
+	"net"
+)
+
+// Obfuscator wraps an underlying net.PacketConn, transforming every
+// packet written or read through it.
+type Obfuscator interface {
+	WrapPacketConn(net.PacketConn) net.PacketConn
+}
+
+// Mode selects which Obfuscator a binary should use, driven by the
+// `transport.mode` config key.
+type Mode string
+
+const (
+	ModePlain     Mode = "plain"
+	ModeXOR       Mode = "xor"
+	ModeFrontedWS Mode = "fronted-ws"
+)
+
+// Config is the YAML shape of the `transport` section shared by the
+// client and server.
+type Config struct {
+	Mode Mode `yaml:"mode"`
+
+	// XORKey seeds the ChaCha20 keystream used by ModeXOR.
+	XORKey string `yaml:"xor_key"`
+
+	// FrontedWS configures ModeFrontedWS.
+	FrontedWS FrontedWSConfig `yaml:"fronted_ws"`
+}
+
+// New builds the Obfuscator selected by cfg.Mode.
+func New(cfg Config) (Obfuscator, error) {
+	switch cfg.Mode {
+	case "", ModePlain:
+		return NullObfuscator{}, nil
+	case ModeXOR:
+		return NewXORObfuscator(cfg.XORKey)
+	case ModeFrontedWS:
+		return NewFrontedWS(cfg.FrontedWS)
+	default:
+		return nil, fmt.Errorf("transport: unknown mode %q", cfg.Mode)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// letsEncryptStagingURL is Let's Encrypt's staging directory, used in
+// place of the production default (acme.LetsEncryptURL) when
+// ACMEConfig.Staging is set so development doesn't burn into the
+// production rate limits.
+const letsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+// withACME wraps base so that SNIs in cfg.Hosts are served an
+// autocert-issued certificate over plain ALPN instead of base's mTLS
+// identity, letting public, ALPN-only clients and internal mTLS clients
+// share the same listener. It also starts the HTTP-01 challenge
+// responder autocert needs on :80.
+func withACME(base *tls.Config, cfg ACMEConfig) *tls.Config {
+	manager := newACMEManager(cfg)
+	go startACMEHTTPResponder(manager)
+
+	mtlsGetConfigForClient := base.GetConfigForClient
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		NextProtos: base.NextProtos,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			if acmeHostAllowed(cfg.Hosts, hello.ServerName) {
+				return &tls.Config{
+					MinVersion:     tls.VersionTLS13,
+					NextProtos:     base.NextProtos,
+					GetCertificate: manager.GetCertificate,
+				}, nil
+			}
+			if mtlsGetConfigForClient != nil {
+				return mtlsGetConfigForClient(hello)
+			}
+			return base, nil
+		},
+	}
+}
+
+// newACMEManager builds an autocert.Manager that provisions certificates
+// from Let's Encrypt (or its staging endpoint, for testing) for cfg's
+// allowlisted hosts, caching them under cfg.CacheDir.
+func newACMEManager(cfg ACMEConfig) *autocert.Manager {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Hosts...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+	}
+	if cfg.Staging {
+		manager.Client = &acme.Client{DirectoryURL: letsEncryptStagingURL}
+	}
+	return manager
+}
+
+// acmeHostAllowed reports whether serverName is one of cfg's allowlisted
+// ACME hosts.
+func acmeHostAllowed(hosts []string, serverName string) bool {
+	for _, h := range hosts {
+		if h == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// startACMEHTTPResponder serves the ACME HTTP-01 challenge on :80
+// alongside the existing metrics server.
+func startACMEHTTPResponder(manager *autocert.Manager) {
+	log.Printf("Starting ACME HTTP-01 responder on :80")
+	if err := http.ListenAndServe(":80", manager.HTTPHandler(nil)); err != nil {
+		log.Printf("ACME HTTP-01 responder stopped: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package main contains the entry point for the StealthQUIC-VPN server.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/tlsconf"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/transport"
+)
+
+// Config holds the server's YAML configuration.
+type Config struct {
+	Server struct {
+		Address        string             `yaml:"address"`
+		TLS            tlsconf.RoleConfig `yaml:"tls"`
+		MetricsAddress string             `yaml:"metrics_address"`
+		QlogDir        string             `yaml:"qlog_dir"`
+		ACME           ACMEConfig         `yaml:"acme"`
+		// AllowedALPN, if non-empty, restricts accepted connections to
+		// ClientHellos presenting at least one of these ALPN protocols,
+		// determined by sniffing the Initial packet before it reaches
+		// quic-go's handshake machinery (see sniff.go). Empty accepts
+		// every ALPN, same as before this existed.
+		AllowedALPN []string `yaml:"allowed_alpn"`
+	} `yaml:"server"`
+
+	FEC struct {
+		Codec         string  `yaml:"codec"` // "tetrys" or "raptorq"
+		MaxRedundancy float64 `yaml:"max_redundancy"`
+	} `yaml:"fec"`
+
+	Encryption outercipher.Config `yaml:"encryption"`
+
+	// TunnelMode selects how TUN PDUs are carried to the client: one of
+	// TunnelModeDatagram (default), TunnelModeStreamMux or
+	// TunnelModeStreamPerPacket.
+	TunnelMode string `yaml:"tunnel_mode"`
+
+	Transport transport.Config `yaml:"transport"`
+
+	Keepalive struct {
+		Interval int `yaml:"interval"`
+	} `yaml:"keepalive"`
+}
+
+// ACMEConfig controls optional Let's Encrypt certificate provisioning,
+// used alongside (not instead of) the static mTLS certificate in
+// Server.TLS: SNIs matching Hosts get an autocert-issued certificate over
+// plain ALPN (no client cert required), while every other SNI still goes
+// through the mTLS path, so internal and public clients can coexist.
+type ACMEConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Email    string   `yaml:"email"`
+	CacheDir string   `yaml:"cache_dir"`
+	Hosts    []string `yaml:"hosts"`
+	Staging  bool     `yaml:"staging"`
+}
+
+// loadConfig loads the server configuration from a YAML file, filling in
+// sane defaults for anything left unset.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if config.FEC.Codec == "" {
+		config.FEC.Codec = "tetrys"
+	}
+	if config.FEC.MaxRedundancy <= 0 {
+		config.FEC.MaxRedundancy = 0.2
+	}
+	if config.Keepalive.Interval <= 0 {
+		config.Keepalive.Interval = 30
+	}
+	if config.Server.ACME.Enabled && config.Server.ACME.CacheDir == "" {
+		config.Server.ACME.CacheDir = "acme-cache"
+	}
+	if config.TunnelMode == "" {
+		config.TunnelMode = TunnelModeDatagram
+	}
+	switch config.TunnelMode {
+	case TunnelModeDatagram, TunnelModeStreamMux, TunnelModeStreamPerPacket:
+	default:
+		return nil, fmt.Errorf("unknown tunnel mode %q", config.TunnelMode)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/quic-go/quic-go"
+	"github.com/songgao/water"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/fec"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/metrics"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+// frameLengthPrefixSize is the size, in bytes, of the length prefix placed
+// in front of every FEC-coded, encrypted PDU - both on the dedicated TUN
+// data stream and, coalesced, inside a QUIC datagram.
+const frameLengthPrefixSize = 2
+
+// maxFrameSize bounds a single frame so a corrupt or malicious length
+// prefix can't make the reader allocate unbounded memory; it is the
+// largest value frameLengthPrefixSize can encode.
+const maxFrameSize = 1<<16 - 1
+
+// conservativeMaxDatagramSize stands in for the peer's actual negotiated
+// QUIC datagram frame size: quic-go's public API reports only whether
+// datagram support was negotiated (ConnectionState.SupportsDatagrams),
+// not the negotiated size itself, so this mirrors the smallest plausible
+// real-world path MTU (1280, IPv6's minimum) minus IP/UDP/QUIC header
+// overhead, rather than a value that could actually be too large for the
+// path.
+const conservativeMaxDatagramSize = 1200
+
+// TunnelMode selects how HandleTUNTraffic carries PDUs to the peer.
+const (
+	// TunnelModeDatagram is the default: a PDU is coalesced into an
+	// unreliable QUIC datagram when it fits within
+	// conservativeMaxDatagramSize and the peer has negotiated datagram
+	// support, and falls back to the dedicated TUN data stream otherwise.
+	TunnelModeDatagram = "datagram"
+	// TunnelModeStreamMux sends every PDU as a length-prefixed frame on
+	// the dedicated TUN data stream, never using datagrams - useful
+	// against a peer or path that can't reliably carry them.
+	TunnelModeStreamMux = "stream-mux"
+	// TunnelModeStreamPerPacket opens a new stream per PDU, closing it
+	// once written. This is the pre-datagram-support behavior, kept only
+	// for compatibility testing against older peers - it pays a full
+	// stream lifecycle per packet and should not be used otherwise.
+	TunnelModeStreamPerPacket = "stream-per-packet"
+)
+
+// perPacketMagic prefixes a one-off stream opened by TunnelModeStreamPerPacket,
+// distinguishing it from a keepalive ping or rotation announcement on the
+// same ad hoc stream accept loop.
+var perPacketMagic = []byte("pdu:")
+
+// datagramMTU reports the conservative datagram size HandleTUNTraffic
+// will actually use for conn, or 0 if the peer never negotiated datagram
+// support - see conservativeMaxDatagramSize's doc comment for why this
+// isn't the peer's real negotiated value.
+func datagramMTU(conn quic.Connection) int {
+	if !conn.ConnectionState().SupportsDatagrams {
+		return 0
+	}
+	return conservativeMaxDatagramSize
+}
+
+// writeFrame writes a length-prefixed frame to w.
+func writeFrame(w io.Writer, payload []byte) error {
+	var prefix [frameLengthPrefixSize]byte
+	binary.BigEndian.PutUint16(prefix[:], uint16(len(payload)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads one length-prefixed frame from r.
+func readFrame(r io.Reader) ([]byte, error) {
+	var prefix [frameLengthPrefixSize]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint16(prefix[:])
+	if int(n) > maxFrameSize {
+		return nil, fmt.Errorf("frame size %d exceeds maximum %d", n, maxFrameSize)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// appendCoalescedFrame appends payload, length-prefixed, to a growing QUIC
+// datagram buffer, so several small PDUs can share one unreliable datagram
+// instead of each needing a frame - and a round trip - of their own.
+func appendCoalescedFrame(datagram, payload []byte) []byte {
+	var prefix [frameLengthPrefixSize]byte
+	binary.BigEndian.PutUint16(prefix[:], uint16(len(payload)))
+	datagram = append(datagram, prefix[:]...)
+	return append(datagram, payload...)
+}
+
+// splitCoalescedFrames reverses appendCoalescedFrame, returning every PDU
+// packed into a received datagram. A malformed trailing prefix is dropped
+// rather than returned as a frame.
+func splitCoalescedFrames(datagram []byte) [][]byte {
+	var frames [][]byte
+	for len(datagram) >= frameLengthPrefixSize {
+		n := int(binary.BigEndian.Uint16(datagram[:frameLengthPrefixSize]))
+		datagram = datagram[frameLengthPrefixSize:]
+		if n > len(datagram) {
+			break
+		}
+		frames = append(frames, datagram[:n])
+		datagram = datagram[n:]
+	}
+	return frames
+}
+
+// HandleTUNTraffic reads IP packets off iface, FEC-encodes and encrypts
+// each one, and sends the resulting PDUs to the peer. A PDU that fits
+// within conservativeMaxDatagramSize, once the peer has negotiated
+// datagram support at all, is coalesced into an unreliable QUIC datagram
+// alongside any others from the same TUN read,
+// including FEC repair symbols, so loss recovery isn't head-of-line
+// blocked behind the reliable stream. Anything too large for a datagram -
+// or every PDU, if the peer never negotiated datagram support - falls
+// back to the dedicated TUN data stream. tunnelMode overrides this choice:
+// TunnelModeStreamMux always uses the dedicated stream, and
+// TunnelModeStreamPerPacket opens (and closes) a dedicated stream for
+// every PDU instead. If oc is non-nil, every PDU also passes through its
+// outer AEAD layer, and a due key rotation is announced to the peer
+// between TUN reads. m is updated with TUN bytes read, repair symbols
+// sent, and bytes processed by the configured cipher.
+func HandleTUNTraffic(ctx context.Context, iface *water.Interface, conn quic.Connection, dataStream quic.Stream, encoder fec.FEC, oc *outercipher.Cipher, tunnelMode string, m *metrics.Metrics) {
+	buffer := make([]byte, 65535)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		n, err := iface.Read(buffer)
+		if err != nil {
+			log.Printf("TUN read error: %v", err)
+			continue
+		}
+		m.AddBytes("tx", n)
+
+		maybeRotateCipher(conn, oc)
+
+		maxDatagram := 0
+		if tunnelMode == TunnelModeDatagram && conn.ConnectionState().SupportsDatagrams {
+			maxDatagram = conservativeMaxDatagramSize
+		}
+		var datagram []byte
+		flush := func() {
+			if len(datagram) == 0 {
+				return
+			}
+			if err := conn.SendDatagram(datagram); err != nil {
+				log.Printf("Failed to send datagram: %v", err)
+			}
+			datagram = nil
+		}
+
+		for _, pkt := range encoder.Encode(buffer[:n]) {
+			encrypted := pkt
+			if oc != nil {
+				encrypted = oc.Seal(pkt)
+				m.CipherBytesTotal.WithLabelValues(oc.Algorithm(), "tx").Add(float64(len(encrypted)))
+			}
+			if fec.IsRepair(pkt) {
+				m.FECRepairSent.Inc()
+			}
+
+			if tunnelMode == TunnelModeStreamPerPacket {
+				if err := sendPerPacketStream(ctx, conn, encrypted); err != nil {
+					log.Printf("Failed to send per-packet stream: %v", err)
+				}
+				continue
+			}
+
+			if maxDatagram <= 0 || frameLengthPrefixSize+len(encrypted) > maxDatagram {
+				if err := writeFrame(dataStream, encrypted); err != nil {
+					log.Printf("Failed to write frame to QUIC stream: %v", err)
+					return
+				}
+				continue
+			}
+
+			if len(datagram)+frameLengthPrefixSize+len(encrypted) > maxDatagram {
+				flush()
+			}
+			datagram = appendCoalescedFrame(datagram, encrypted)
+		}
+		flush()
+	}
+}
+
+// sendPerPacketStream opens a dedicated stream for a single PDU, tagged
+// so the peer's control stream accept loop can recognize it, and closes
+// the stream once written. Used only by TunnelModeStreamPerPacket.
+func sendPerPacketStream(ctx context.Context, conn quic.Connection, payload []byte) error {
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open per-packet stream: %w", err)
+	}
+	defer safestream.Wrap(stream).Close()
+	_, err = stream.Write(append(append([]byte{}, perPacketMagic...), payload...))
+	return err
+}
+
+// HandleQUICDataStream reads framed PDUs from the dedicated TUN data
+// stream, decrypts and FEC-decodes them, and writes any recovered IP
+// packets back to iface. It carries every PDU too large to fit in a
+// datagram, and every PDU at all when the peer uses TunnelModeStreamMux or
+// has no datagram support; the rest arrive via HandleQUICDatagrams. m is
+// updated with bytes received, cipher throughput, recovered packets, and
+// decryption failures.
+func HandleQUICDataStream(ctx context.Context, dataStream quic.Stream, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher, m *metrics.Metrics) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		frame, err := readFrame(dataStream)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("TUN data stream read error: %v", err)
+			}
+			return
+		}
+		m.AddBytes("rx", len(frame))
+
+		decrypted, err := decryptPDU(frame, oc, m)
+		if err != nil {
+			continue
+		}
+
+		recovered := decoder.Decode(decrypted)
+		if fec.IsRepair(decrypted) {
+			m.FECDecodeSuccess.Add(float64(len(recovered)))
+		}
+		for _, pkt := range recovered {
+			if _, err := iface.Write(pkt); err != nil {
+				log.Printf("Failed to write to TUN: %v", err)
+			}
+		}
+	}
+}
+
+// HandleQUICDatagrams receives coalesced QUIC datagrams, decrypts and
+// FEC-decodes each coalesced PDU, and writes any recovered IP packets
+// back to iface. m is updated the same way as HandleQUICDataStream.
+func HandleQUICDatagrams(ctx context.Context, conn quic.Connection, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher, m *metrics.Metrics) {
+	for {
+		datagram, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Datagram receive error: %v", err)
+			}
+			return
+		}
+		m.AddBytes("rx", len(datagram))
+
+		for _, frame := range splitCoalescedFrames(datagram) {
+			decrypted, err := decryptPDU(frame, oc, m)
+			if err != nil {
+				continue
+			}
+			recovered := decoder.Decode(decrypted)
+			if fec.IsRepair(decrypted) {
+				m.FECDecodeSuccess.Add(float64(len(recovered)))
+			}
+			for _, pkt := range recovered {
+				if _, err := iface.Write(pkt); err != nil {
+					log.Printf("Failed to write to TUN: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// decryptPDU reverses the outer cipher layer, if one is configured,
+// recording cipher throughput on success and bumping m.EncryptionFailures
+// on failure.
+func decryptPDU(frame []byte, oc *outercipher.Cipher, m *metrics.Metrics) ([]byte, error) {
+	if oc == nil {
+		return frame, nil
+	}
+	decrypted, err := oc.Open(frame)
+	if err != nil {
+		log.Printf("Decryption failed: %v", err)
+		m.EncryptionFailures.Inc()
+		return nil, err
+	}
+	m.CipherBytesTotal.WithLabelValues(oc.Algorithm(), "rx").Add(float64(len(frame)))
+	return decrypted, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+)
+
+// startKeepaliveTicker periodically sends a keepalive message over a
+// short-lived stream, detaching cleanly when ctx is cancelled.
+func startKeepaliveTicker(ctx context.Context, conn quic.Connection, interval int) {
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sendKeepalive(conn); err != nil {
+				log.Printf("keepalive failed: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// sendKeepalive opens a short-lived stream to signal the peer is alive.
+func sendKeepalive(conn quic.Connection) error {
+	stream, err := conn.OpenStream()
+	if err != nil {
+		return err
+	}
+	defer safestream.Wrap(stream).Close()
+	_, err = stream.Write([]byte("keepalive"))
+	return err
+}
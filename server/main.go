@@ -0,0 +1,240 @@
+// Package main contains the entry point for the StealthQUIC-VPN server.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/songgao/water"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/fec"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/metrics"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/outercipher"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/safestream"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/tlsconf"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/transport"
+)
+
+func main() {
+	configPath := flag.String("config", "server/config.yaml", "Path to server configuration file")
+	flag.Parse()
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", config.Server.Address)
+	if err != nil {
+		log.Fatalf("Failed to resolve UDP address: %v", err)
+	}
+	udpListener, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		log.Fatalf("Failed to listen on UDP: %v", err)
+	}
+
+	m := metrics.New()
+
+	obfuscator, err := transport.New(config.Transport)
+	if err != nil {
+		log.Fatalf("Failed to set up transport obfuscator: %v", err)
+	}
+	packetConn := obfuscator.WrapPacketConn(udpListener)
+	packetConn = wrapSniffing(packetConn, config.Server.AllowedALPN, m)
+
+	iface, err := SetupTUN(udpListener)
+	if err != nil {
+		log.Fatalf("Failed to setup TUN interface: %v", err)
+	}
+
+	tlsManager, err := newTLSManager(config.Server.TLS)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS manager: %v", err)
+	}
+
+	tlsConfig := tlsManager.TLSConfig([]string{"quicfuscate"})
+	if config.Server.ACME.Enabled {
+		tlsConfig = withACME(tlsConfig, config.Server.ACME)
+	}
+
+	quicListener, err := quic.Listen(packetConn, tlsConfig, generateQUICConfig(config.Server.QlogDir, m))
+	if err != nil {
+		log.Fatalf("Failed to start QUIC listener: %v", err)
+	}
+	defer quicListener.Close()
+
+	metricsAddr := config.Server.MetricsAddress
+	if metricsAddr == "" {
+		metricsAddr = ":8080"
+	}
+	go startMetricsServer(metricsAddr, m)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	var wg sync.WaitGroup
+	for {
+		select {
+		case <-interrupt:
+			log.Println("Shutting down server...")
+			wg.Wait()
+			return
+		default:
+			conn, err := quicListener.Accept(context.Background())
+			if err != nil {
+				log.Printf("Failed to accept connection: %v", err)
+				continue
+			}
+			wg.Add(1)
+			go handleConnection(conn, &wg, iface, config, m)
+		}
+	}
+}
+
+// handleConnection drives a single QUIC connection: the first stream the
+// peer opens is treated as the dedicated, full-duplex TUN data stream;
+// every subsequent stream is handled as ad hoc control traffic (e.g.
+// keepalives).
+func handleConnection(conn quic.Connection, wg *sync.WaitGroup, iface *water.Interface, config *Config, m *metrics.Metrics) {
+	defer wg.Done()
+	m.AcceptedConnections.Inc()
+
+	// The QUIC connection ID is only surfaced to the qlog Tracer
+	// callback, not here, so the remote address is used as the
+	// /debug/connections key instead - good enough for a debug endpoint,
+	// since a given client only holds one connection open at a time.
+	connID := conn.RemoteAddr().String()
+
+	defer func() {
+		m.UnregisterConnection(connID)
+		if err := conn.CloseWithError(0, "server closing connection"); err != nil {
+			log.Printf("Failed to close connection: %v", err)
+		}
+	}()
+
+	if cert, ok := tlsconf.PeerCertificate(conn.ConnectionState().TLS); ok {
+		if spiffeID, ok := tlsconf.SPIFFEID(cert); ok {
+			log.Printf("Peer %s authenticated as %s", conn.RemoteAddr(), spiffeID)
+		}
+	}
+
+	m.RegisterConnection(metrics.ConnectionInfo{
+		ID:         connID,
+		RemoteAddr: connID,
+		ALPN:       conn.ConnectionState().TLS.NegotiatedProtocol,
+		// quic-go does not currently expose the congestion controller's
+		// state publicly; this is a placeholder until it does.
+		CongestionState: "unknown",
+		// quic-go does not expose the peer's actual negotiated datagram
+		// frame size either, only whether datagram support was
+		// negotiated at all; runPMTUD (pmtud.go) overwrites this with a
+		// confirmed PLPMTU shortly after the connection is established.
+		DatagramMTU: datagramMTU(conn),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dataStream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		log.Printf("Failed to accept TUN data stream: %v", err)
+		return
+	}
+	m.ActiveStreams.Inc()
+	defer m.ActiveStreams.Dec()
+
+	oc, err := negotiateOuterCipher(dataStream, config.Encryption)
+	if err != nil {
+		log.Printf("Failed to negotiate outer cipher: %v", err)
+		return
+	}
+
+	encoder, decoder, err := newFECPair(config.FEC.Codec, config.FEC.MaxRedundancy)
+	if err != nil {
+		log.Printf("Failed to set up FEC codec: %v", err)
+		return
+	}
+	safeDecoder := fec.NewSafeDecoder(decoder)
+
+	go HandleTUNTraffic(ctx, iface, conn, dataStream, encoder, oc, config.TunnelMode, m)
+	go HandleQUICDataStream(ctx, dataStream, iface, safeDecoder, oc, m)
+	go HandleQUICDatagrams(ctx, conn, iface, safeDecoder, oc, m)
+	go startKeepaliveTicker(ctx, conn, config.Keepalive.Interval)
+	go runPMTUD(ctx, conn, connID, m)
+
+	for {
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Failed to accept stream: %v", err)
+			}
+			return
+		}
+		m.ActiveStreams.Inc()
+		go handleControlStream(stream, iface, safeDecoder, oc, m)
+	}
+}
+
+// handleControlStream services ad hoc streams, such as keepalive pings,
+// key rotation announcements, DPLPMTUD probes and - under
+// TunnelModeStreamPerPacket - individual TUN PDUs, that are not part of
+// the dedicated TUN data stream.
+func handleControlStream(raw quic.Stream, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher, m *metrics.Metrics) {
+	defer m.ActiveStreams.Dec()
+	stream := safestream.Wrap(raw)
+	defer stream.Close()
+	buffer := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buffer)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Control stream read error: %v", err)
+			}
+			return
+		}
+		switch {
+		case string(buffer[:n]) == "keepalive":
+			if _, err := stream.Write([]byte("alive")); err != nil {
+				log.Printf("Control stream write error: %v", err)
+				return
+			}
+		case len(rotationMagic) <= n && string(buffer[:len(rotationMagic)]) == string(rotationMagic):
+			handleRotationAnnouncement(oc, buffer[len(rotationMagic):n])
+		case len(perPacketMagic) <= n && string(buffer[:len(perPacketMagic)]) == string(perPacketMagic):
+			handlePerPacketPDU(buffer[len(perPacketMagic):n], iface, decoder, oc, m)
+		case len(probeMagic) <= n && string(buffer[:len(probeMagic)]) == string(probeMagic):
+			if _, err := stream.Write(probeAck); err != nil {
+				log.Printf("Control stream write error: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// handlePerPacketPDU decrypts and FEC-decodes a single TUN PDU received
+// over a TunnelModeStreamPerPacket stream and writes any recovered IP
+// packets back to iface, the same way HandleQUICDataStream does for the
+// dedicated stream.
+func handlePerPacketPDU(frame []byte, iface *water.Interface, decoder fec.FEC, oc *outercipher.Cipher, m *metrics.Metrics) {
+	m.AddBytes("rx", len(frame))
+	decrypted, err := decryptPDU(frame, oc, m)
+	if err != nil {
+		return
+	}
+	recovered := decoder.Decode(decrypted)
+	if fec.IsRepair(decrypted) {
+		m.FECDecodeSuccess.Add(float64(len(recovered)))
+	}
+	for _, pkt := range recovered {
+		if _, err := iface.Write(pkt); err != nil {
+			log.Printf("Failed to write to TUN: %v", err)
+		}
+	}
+}
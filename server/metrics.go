@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/metrics"
+)
+
+// startMetricsServer serves health checks, Prometheus metrics, qlog
+// tailing and pprof profiles on addr.
+func startMetricsServer(addr string, m *metrics.Metrics) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	mux.Handle("/metrics", promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{}))
+
+	mux.HandleFunc("/qlog/", func(w http.ResponseWriter, r *http.Request) {
+		connID := strings.TrimPrefix(r.URL.Path, "/qlog/")
+		serveQlogTail(w, r, m, connID)
+	})
+
+	mux.HandleFunc("/debug/connections", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.Connections()); err != nil {
+			log.Printf("Failed to encode /debug/connections response: %v", err)
+		}
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	log.Printf("Starting metrics server on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("metrics server stopped: %v", err)
+	}
+}
+
+// serveQlogTail streams a connection's qlog file as newline-delimited
+// JSON over chunked HTTP, following new lines as they're appended until
+// the client disconnects.
+func serveQlogTail(w http.ResponseWriter, r *http.Request, m *metrics.Metrics, connID string) {
+	path, ok := m.QlogPath(connID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, canFlush := w.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		default:
+		}
+
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return
+		}
+	}
+}
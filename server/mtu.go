@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net"
+)
+
+// adaptiveMTUDetect is called once, at startup, to size the TUN interface
+// that every QUIC connection the server later accepts will share - so it
+// always reports the conservative default rather than running pkg/pmtud's
+// real DPLPMTUD state machine itself: there is no peer yet to probe, and
+// the TUN interface can't be resized afterward to fit any one connection's
+// path anyway. Real, per-connection DPLPMTUD runs once a connection
+// exists - see runPMTUD in pmtud.go, started from handleConnection - and
+// its confirmed PLPMTU is surfaced at /debug/connections, independent of
+// this placeholder.
+func adaptiveMTUDetect(conn net.Conn) (int, error) {
+	return 1400, nil
+}
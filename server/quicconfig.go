@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/metrics"
+)
+
+// generateQUICConfig returns the quic.Config shared by every server
+// listener. Every connection gets a qlog trace written under qlogDir and
+// registered with m so it can be tailed at /qlog/{conn_id}.
+func generateQUICConfig(qlogDir string, m *metrics.Metrics) *quic.Config {
+	if qlogDir == "" {
+		qlogDir = "."
+	}
+
+	return &quic.Config{
+		EnableDatagrams: true,
+		Tracer: func(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+			id := connID.String()
+			path := filepath.Join(qlogDir, fmt.Sprintf("server_%s.qlog", id))
+
+			f, err := os.Create(path)
+			if err != nil {
+				log.Printf("Failed to create qlog file %s: %v", path, err)
+				return nil
+			}
+			m.RegisterQlogPath(id, path)
+
+			return qlog.NewConnectionTracer(f, p, connID)
+		},
+	}
+}
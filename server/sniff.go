@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"net"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/metrics"
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/quicsniff"
+)
+
+// wrapSniffing wraps local with a net.PacketConn that parses every long
+// -header Initial packet with quicsniff before quic-go ever sees it, so
+// the server can tell a real QUIC handshake attempt from active-probe
+// scan noise: a payload that claims to be an Initial packet but doesn't
+// decrypt and parse as one is dropped right here rather than spent on
+// quic-go's handshake state machine. Packets quicsniff doesn't recognize
+// as Initial at all (short-header 1-RTT packets on an already-established
+// connection, for instance) are passed through untouched.
+//
+// If allowedALPN is non-empty, a ClientHello presenting none of those
+// protocols is dropped the same way a scan is - with only one quic.Listener
+// configured today, this is the honest degenerate case of "route by ALPN
+// to a different listener": accept or reject, since there's nowhere else
+// to route to yet.
+func wrapSniffing(local net.PacketConn, allowedALPN []string, m *metrics.Metrics) net.PacketConn {
+	return &sniffingPacketConn{PacketConn: local, allowedALPN: allowedALPN, m: m}
+}
+
+type sniffingPacketConn struct {
+	net.PacketConn
+	allowedALPN []string
+	m           *metrics.Metrics
+}
+
+func (c *sniffingPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return n, addr, err
+		}
+
+		info, err := quicsniff.Parse(p[:n])
+		switch {
+		case err == quicsniff.ErrNotInitial:
+			return n, addr, nil
+		case err != nil:
+			log.Printf("Dropping non-QUIC or malformed Initial packet from %s: %v", addr, err)
+			c.m.ScanPacketsDropped.Inc()
+			continue
+		}
+
+		log.Printf("QUIC Initial from %s: sni=%q alpn=%v version=%#x", addr, info.SNI, info.ALPN, info.Version)
+
+		if !alpnAllowed(info.ALPN, c.allowedALPN) {
+			log.Printf("Dropping Initial from %s: ALPN %v not in allowed list %v", addr, info.ALPN, c.allowedALPN)
+			c.m.ScanPacketsDropped.Inc()
+			continue
+		}
+
+		return n, addr, nil
+	}
+}
+
+// alpnAllowed reports whether offered shares at least one protocol with
+// allowed, or allowed is empty (meaning every ALPN is accepted).
+func alpnAllowed(offered, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, want := range allowed {
+		for _, got := range offered {
+			if want == got {
+				return true
+			}
+		}
+	}
+	return false
+}
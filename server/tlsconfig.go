@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Christopher-Schulze/QuicFuscate/pkg/tlsconf"
+)
+
+// newTLSManager builds the server's mutual-auth TLS manager and starts its
+// hot-reload loop: the trusted client CA pool is rotated on SIGHUP or when
+// the CA bundle's mtime changes, without dropping existing connections.
+func newTLSManager(cfg tlsconf.RoleConfig) (*tlsconf.Manager, error) {
+	manager, err := tlsconf.NewManager(tlsconf.RoleServer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go manager.WatchReload(nil, sighup, 0)
+
+	return manager, nil
+}
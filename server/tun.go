@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/songgao/water"
+)
+
+// SetupTUN creates a TUN interface and logs the MTU detected on conn (1400
+// if detection fails). The interface's own MTU is not changed here.
+func SetupTUN(conn net.Conn) (*water.Interface, error) {
+	config := water.Config{
+		DeviceType: water.TUN,
+	}
+
+	iface, err := water.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN interface: %w", err)
+	}
+
+	mtu, err := adaptiveMTUDetect(conn)
+	if err != nil {
+		log.Printf("Failed to detect MTU: %v", err)
+		mtu = 1400
+	}
+
+	log.Printf("TUN interface %s created with MTU %d", iface.Name(), mtu)
+	return iface, nil
+}